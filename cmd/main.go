@@ -1,7 +1,17 @@
 package main
 
-import "phoenixbot/internal/bot"
+import (
+	"flag"
+	"phoenixbot/internal/bot"
+)
 
 func main() {
+	dryRun := flag.Bool("sync-commands-dry-run", false, "Log how the registered slash commands would change and exit")
+	flag.Parse()
+
+	if *dryRun {
+		bot.SyncCommandsDryRun()
+		return
+	}
 	bot.Run()
 }
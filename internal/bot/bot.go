@@ -7,15 +7,21 @@ import (
 	"phoenixbot/internal/cog"
 	"phoenixbot/internal/config"
 	"phoenixbot/internal/discord"
-	"sync"
 	"syscall"
+
+	"github.com/bwmarrin/discordgo"
 )
 
 func Run() {
 	config.Load()
 
 	discord.Init()
-	initCogs()
+	cogs := initCogs()
+
+	discord.Session.AddHandlerOnce(func(s *discordgo.Session, r *discordgo.Ready) {
+		cog.SyncCommands(s, r.User.ID, cogs, config.Configuration.SyncGlobalCommands, false)
+	})
+
 	discord.InitConnection()
 
 	defer func() {
@@ -33,7 +39,23 @@ func Run() {
 	config.Logger.Infoln("Shutdown signal received.")
 }
 
-func initCogs() {
+// SyncCommandsDryRun logs how the registered application commands would change without
+// changing them or connecting the bot to the gateway.
+func SyncCommandsDryRun() {
+	config.Load()
+
+	discord.Init()
+	cogs := initCogs()
+
+	app, err := discord.Session.Application("@me")
+	if err != nil {
+		config.Logger.Fatal("Failed to get the application of the bot: ", err)
+	}
+
+	cog.SyncCommands(discord.Session, app.ID, cogs, config.Configuration.SyncGlobalCommands, true)
+}
+
+func initCogs() []cog.Cog {
 
 	if discord.Session == nil {
 		config.Logger.Panic("Tried to init cogs before initializing discord session")
@@ -41,9 +63,8 @@ func initCogs() {
 
 	cogList := []cog.Cog{
 		&cog.TicketCog{
-			ConfigName:  "ticket.json5",
-			Session:     discord.Session,
-			TicketUsers: sync.Map{},
+			ConfigName: "ticket.json5",
+			Session:    discord.Session,
 		},
 		&cog.CommandCog{
 			ConfigName: "command.json5",
@@ -62,4 +83,5 @@ func initCogs() {
 			config.Logger.Fatal("Error initializing cog:", c.Name(), err)
 		}
 	}
+	return cogList
 }
@@ -1,6 +1,24 @@
 package cog
 
+import (
+	"fmt"
+	"phoenixbot/internal/config"
+	"phoenixbot/internal/discord"
+)
+
 type Cog interface {
 	Name() string
 	Init() error
 }
+
+// validateMessages checks the messages of a loaded config and logs every problem with its path.
+func validateMessages(configName string, conf interface{}) error {
+	errs := discord.ValidateMessages(conf)
+	for _, err := range errs {
+		config.Logger.Errorf("Invalid message in %s: %v", configName, err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s has %d invalid messages", configName, len(errs))
+	}
+	return nil
+}
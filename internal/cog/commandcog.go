@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"phoenixbot/internal/config"
 	"phoenixbot/internal/discord"
+	"sort"
+	"strconv"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -13,6 +15,41 @@ type CommandData struct {
 	Description     string              `json:"Description"`
 	AllowedChannels map[string]string   `json:"Allowed_channels"` // Allowed channels (name and ID)
 	Response        discord.MessageData `json:"Response"`
+
+	Options   []CommandOption   `json:"Options"`
+	Responses []CommandResponse `json:"Responses"` // Used instead of Response when their option values match
+
+	// Subcommands replace the options and responses of the command. Subcommands with
+	// their own Subcommands become groups, discord allows no deeper nesting.
+	Subcommands map[string]CommandData `json:"Subcommands"`
+}
+
+type CommandOption struct {
+	Name        string          `json:"Name"`
+	Description string          `json:"Description"`
+	Type        string          `json:"Type"` // string, integer, number, boolean, user, channel or role
+	Required    bool            `json:"Required"`
+	Choices     []CommandChoice `json:"Choices"`
+}
+
+type CommandChoice struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"` // Converted to the type of the option
+}
+
+type CommandResponse struct {
+	When     map[string]string   `json:"When"` // Maps option name to the value it must have
+	Response discord.MessageData `json:"Response"`
+}
+
+var commandOptionTypes = map[string]discordgo.ApplicationCommandOptionType{
+	"string":  discordgo.ApplicationCommandOptionString,
+	"integer": discordgo.ApplicationCommandOptionInteger,
+	"number":  discordgo.ApplicationCommandOptionNumber,
+	"boolean": discordgo.ApplicationCommandOptionBoolean,
+	"user":    discordgo.ApplicationCommandOptionUser,
+	"channel": discordgo.ApplicationCommandOptionChannel,
+	"role":    discordgo.ApplicationCommandOptionRole,
 }
 
 type CommandGuildConfig struct {
@@ -21,7 +58,8 @@ type CommandGuildConfig struct {
 }
 
 type CommandConfig struct {
-	Guilds map[string]*CommandGuildConfig `json:"Guilds"`
+	Guilds          map[string]*CommandGuildConfig `json:"Guilds"`
+	Global_commands map[string]CommandData         `json:"Global_commands"` // Registered when SyncGlobalCommands is set in config.json5
 }
 
 type CommandCog struct {
@@ -45,21 +83,17 @@ func (m *CommandCog) Init() error {
 	}
 	m.Config = &commandConfig
 
+	if err := validateMessages(m.ConfigName, m.Config); err != nil {
+		return err
+	}
+
 	for guild, com := range m.Config.Guilds {
 		if !config.IsGuildEnabled(guild) {
 			continue
 		}
 		if !com.Enabled {
 			config.Logger.Infoln("Command feature disabled in config, on server ", guild)
-			continue
 		}
-
-		m.Session.AddHandlerOnce(func(s *discordgo.Session, r *discordgo.Ready) {
-			config.Logger.Infoln("Registering commands for server", guild)
-			if err := m.registerCommands(guild); err != nil {
-				config.Logger.Errorf("Failed to register commands: %v", err)
-			}
-		})
 	}
 
 	m.Session.AddHandler(m.HandleInteraction)
@@ -68,37 +102,50 @@ func (m *CommandCog) Init() error {
 	return nil
 }
 
-func (m *CommandCog) registerCommands(guildID string) error {
+func (m *CommandCog) CommandsLoaded() bool {
+	return m.Config != nil
+}
 
-	conf, ok := m.Config.Guilds[guildID]
-	if !ok {
-		return fmt.Errorf("couldnt find command config for guild %s", guildID)
+// Commands returns the enabled configured commands of the guild, or the global commands.
+func (m *CommandCog) Commands(guildID string) []*discordgo.ApplicationCommand {
+	if m.Config == nil {
+		return nil
+	}
+	commands := m.Config.Global_commands
+	if guildID != "" {
+		conf, ok := m.Config.Guilds[guildID]
+		if !ok || !conf.Enabled || !config.IsGuildEnabled(guildID) {
+			return nil
+		}
+		commands = conf.Commands
 	}
 
-	if m.Config == nil || !conf.Enabled {
-		return nil
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	for name, command := range conf.Commands {
+	appCommands := []*discordgo.ApplicationCommand{}
+	for _, name := range names {
+		command := commands[name]
 		if !command.Enabled {
 			continue
 		}
 
-		appCommand := &discordgo.ApplicationCommand{
-			Name:        name,
-			Description: command.Description,
-		}
-
-		_, err := m.Session.ApplicationCommandCreate(m.Session.State.User.ID, guildID, appCommand)
+		options, err := command.applicationOptions()
 		if err != nil {
-			config.Logger.Errorf("Failed to register command '%s': %v", name, err)
-			return err
+			config.Logger.Errorf("Invalid options for command '%s': %v", name, err)
+			continue
 		}
 
-		config.Logger.Infoln("Succesfully registered command: ", name)
+		appCommands = append(appCommands, &discordgo.ApplicationCommand{
+			Name:        name,
+			Description: command.Description,
+			Options:     options,
+		})
 	}
-
-	return nil
+	return appCommands
 }
 
 func (m *CommandCog) HandleInteraction(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
@@ -106,19 +153,38 @@ func (m *CommandCog) HandleInteraction(session *discordgo.Session, interaction *
 		return
 	}
 
-	conf, ok := m.Config.Guilds[interaction.GuildID]
-	if !ok {
-		config.Logger.Warnln("Couldnt find command config for guild ", interaction.GuildID)
-		return
-	}
-
+	// Guild commands take precedence over global commands with the same name
 	commandName := interaction.ApplicationCommandData().Name
-	command, exists := conf.Commands[commandName]
+	command, exists := m.Config.Global_commands[commandName]
+	if conf, ok := m.Config.Guilds[interaction.GuildID]; ok {
+		if guildCommand, ok := conf.Commands[commandName]; ok {
+			command, exists = guildCommand, true
+		}
+	}
 	if !exists || !command.Enabled {
 		return
 	}
 
-	if !isChannelAllowed(interaction.ChannelID, command.AllowedChannels) {
+	// Follow the used subcommand, subcommands without channels of their own inherit them
+	dataOptions := interaction.ApplicationCommandData().Options
+	allowedChannels := command.AllowedChannels
+	for len(command.Subcommands) > 0 && len(dataOptions) == 1 {
+		option := dataOptions[0]
+		if option.Type != discordgo.ApplicationCommandOptionSubCommand && option.Type != discordgo.ApplicationCommandOptionSubCommandGroup {
+			break
+		}
+
+		command, exists = command.Subcommands[option.Name]
+		if !exists || !command.Enabled {
+			return
+		}
+		if len(command.AllowedChannels) > 0 {
+			allowedChannels = command.AllowedChannels
+		}
+		dataOptions = option.Options
+	}
+
+	if !isChannelAllowed(interaction.ChannelID, allowedChannels) {
 		_ = session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
@@ -129,11 +195,27 @@ func (m *CommandCog) HandleInteraction(session *discordgo.Session, interaction *
 		return
 	}
 
-	ms, err := discord.CreateMessageSend(command.Response)
+	user := interaction.User
+	if interaction.Member != nil {
+		user = interaction.Member.User
+	}
+
+	// Option values are available as placeholders named after the option
+	values := discord.TemplateValuesFor(session, interaction.GuildID, interaction.ChannelID, user)
+	options := commandOptionValues(dataOptions)
+	for name, value := range options {
+		values[name] = value.Display
+	}
+
+	ms, err := discord.CreateMessageSend(command.response(options), values)
 	if err != nil {
 		config.Logger.Errorln(err)
 		return
 	}
+	// Option values are typed by the user, so they must not ping roles or everyone
+	ms.AllowedMentions = &discordgo.MessageAllowedMentions{
+		Parse: []discordgo.AllowedMentionType{discordgo.AllowedMentionTypeUsers},
+	}
 
 	err = discord.SendInteractionResponse(session, interaction.Interaction, ms)
 	if err != nil {
@@ -141,6 +223,143 @@ func (m *CommandCog) HandleInteraction(session *discordgo.Session, interaction *
 	}
 }
 
+// applicationOptions converts the configured options or subcommands to discord command options.
+func (command CommandData) applicationOptions() ([]*discordgo.ApplicationCommandOption, error) {
+	if len(command.Subcommands) > 0 {
+		return command.subcommandOptions(0)
+	}
+
+	options := []*discordgo.ApplicationCommandOption{}
+	for _, option := range command.Options {
+		optionType, ok := commandOptionTypes[option.Type]
+		if !ok {
+			return nil, fmt.Errorf("option %s has unknown type %q", option.Name, option.Type)
+		}
+
+		appOption := &discordgo.ApplicationCommandOption{
+			Type:        optionType,
+			Name:        option.Name,
+			Description: option.Description,
+			Required:    option.Required,
+		}
+
+		for _, choice := range option.Choices {
+			var value interface{} = choice.Value
+			switch optionType {
+			case discordgo.ApplicationCommandOptionInteger:
+				parsed, err := strconv.ParseInt(choice.Value, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("choice %s of option %s is not an integer", choice.Name, option.Name)
+				}
+				value = parsed
+			case discordgo.ApplicationCommandOptionNumber:
+				parsed, err := strconv.ParseFloat(choice.Value, 64)
+				if err != nil {
+					return nil, fmt.Errorf("choice %s of option %s is not a number", choice.Name, option.Name)
+				}
+				value = parsed
+			case discordgo.ApplicationCommandOptionString:
+			default:
+				return nil, fmt.Errorf("option %s of type %s cant have choices", option.Name, option.Type)
+			}
+
+			appOption.Choices = append(appOption.Choices, &discordgo.ApplicationCommandOptionChoice{
+				Name:  choice.Name,
+				Value: value,
+			})
+		}
+
+		options = append(options, appOption)
+	}
+	return options, nil
+}
+
+// subcommandOptions converts the enabled subcommands, depth is the nesting of the command itself.
+func (command CommandData) subcommandOptions(depth int) ([]*discordgo.ApplicationCommandOption, error) {
+	names := make([]string, 0, len(command.Subcommands))
+	for name := range command.Subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	options := []*discordgo.ApplicationCommandOption{}
+	for _, name := range names {
+		subcommand := command.Subcommands[name]
+		if !subcommand.Enabled {
+			continue
+		}
+
+		option := &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        name,
+			Description: subcommand.Description,
+		}
+
+		var err error
+		if len(subcommand.Subcommands) > 0 {
+			if depth > 0 {
+				return nil, fmt.Errorf("subcommand group %s cant have subcommands with subcommands", name)
+			}
+			option.Type = discordgo.ApplicationCommandOptionSubCommandGroup
+			option.Options, err = subcommand.subcommandOptions(depth + 1)
+		} else {
+			option.Options, err = subcommand.applicationOptions()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		options = append(options, option)
+	}
+	return options, nil
+}
+
+// commandOptionValue is an option value used by the interaction.
+type commandOptionValue struct {
+	Value   string // Compared against the When of responses
+	Display string // Shown in placeholders, users, channels and roles are mentioned
+}
+
+func commandOptionValues(options []*discordgo.ApplicationCommandInteractionDataOption) map[string]commandOptionValue {
+	values := make(map[string]commandOptionValue)
+	for _, option := range options {
+		value := fmt.Sprint(option.Value)
+		display := value
+
+		switch option.Type {
+		case discordgo.ApplicationCommandOptionInteger:
+			value = strconv.FormatInt(option.IntValue(), 10)
+			display = value
+		case discordgo.ApplicationCommandOptionUser:
+			display = "<@" + value + ">"
+		case discordgo.ApplicationCommandOptionChannel:
+			display = "<#" + value + ">"
+		case discordgo.ApplicationCommandOptionRole:
+			display = "<@&" + value + ">"
+		}
+
+		values[option.Name] = commandOptionValue{Value: value, Display: display}
+	}
+	return values
+}
+
+// response returns the first of the Responses whose option values match, or the default Response.
+func (command CommandData) response(options map[string]commandOptionValue) discord.MessageData {
+	for _, response := range command.Responses {
+		matches := true
+		for name, want := range response.When {
+			if option, ok := options[name]; !ok || option.Value != want {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return response.Response
+		}
+	}
+	return command.Response
+}
+
 func isChannelAllowed(channelID string, allowedChannels map[string]string) bool {
 	if len(allowedChannels) == 0 {
 		return true
@@ -0,0 +1,235 @@
+package cog
+
+import (
+	"encoding/json"
+	"fmt"
+	"phoenixbot/internal/config"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// CommandProvider is a cog with application commands. All commands of a guild are
+// registered together, so every cog with commands has to provide them here.
+type CommandProvider interface {
+	// Commands returns the commands of the guild, an empty guild id asks for global commands.
+	Commands(guildID string) []*discordgo.ApplicationCommand
+	// CommandsLoaded reports whether the config of the commands was loaded. Scopes are not
+	// synced while a provider isnt loaded, the overwrite would delete its commands.
+	CommandsLoaded() bool
+}
+
+// commandNamePattern is the name format discord accepts for commands and options.
+var commandNamePattern = regexp.MustCompile(`^[-_\p{Ll}\p{Lo}\p{N}]{1,32}$`)
+
+// commandSyncPlan is the difference between the wanted and the registered commands of one scope.
+type commandSyncPlan struct {
+	Create    []string
+	Update    []string
+	Delete    []string
+	Unchanged int
+}
+
+func (plan commandSyncPlan) empty() bool {
+	return len(plan.Create) == 0 && len(plan.Update) == 0 && len(plan.Delete) == 0
+}
+
+// SyncCommands overwrites the registered commands of every enabled guild, and the global
+// commands if global is set, with the commands of the cogs. Stale commands are removed.
+// With dryRun the plan is only logged.
+func SyncCommands(session *discordgo.Session, appID string, cogs []Cog, global bool, dryRun bool) {
+	scopes := []string{}
+	for guildID, enabled := range config.Configuration.Guilds {
+		if enabled {
+			scopes = append(scopes, guildID)
+		}
+	}
+	sort.Strings(scopes)
+	if global {
+		scopes = append(scopes, "")
+	}
+
+	for _, guildID := range scopes {
+		if err := syncScope(session, appID, guildID, cogs, dryRun); err != nil {
+			config.Logger.Errorf("Failed to sync commands of %s: %v", scopeName(guildID), err)
+		}
+	}
+}
+
+func syncScope(session *discordgo.Session, appID, guildID string, cogs []Cog, dryRun bool) error {
+	wanted := []*discordgo.ApplicationCommand{}
+	names := make(map[string]string)
+	for _, c := range cogs {
+		provider, ok := c.(CommandProvider)
+		if !ok {
+			continue
+		}
+		if !provider.CommandsLoaded() {
+			return fmt.Errorf("config of %s isnt loaded, keeping the registered commands", c.Name())
+		}
+		for _, command := range provider.Commands(guildID) {
+			if owner, ok := names[command.Name]; ok {
+				config.Logger.Warnf("Command '%s' of %s is already provided by %s, skipping it", command.Name, c.Name(), owner)
+				continue
+			}
+			if err := validateCommand(command); err != nil {
+				config.Logger.Errorf("Invalid command '%s' of %s, skipping it: %v", command.Name, c.Name(), err)
+				continue
+			}
+			names[command.Name] = c.Name()
+			wanted = append(wanted, command)
+		}
+	}
+
+	registered, err := session.ApplicationCommands(appID, guildID)
+	if err != nil {
+		return err
+	}
+
+	plan := diffCommands(wanted, registered)
+	config.Logger.Infof("Command sync plan for %s: create %v, update %v, delete %v, %d unchanged",
+		scopeName(guildID), plan.Create, plan.Update, plan.Delete, plan.Unchanged)
+
+	if dryRun || plan.empty() {
+		return nil
+	}
+
+	_, err = session.ApplicationCommandBulkOverwrite(appID, guildID, wanted)
+	if err != nil {
+		return err
+	}
+	config.Logger.Infoln("Synced", len(wanted), "commands of", scopeName(guildID))
+	return nil
+}
+
+// validateCommand checks the limits discord enforces on a command, an invalid command
+// would make the bulk overwrite of the whole scope fail.
+func validateCommand(command *discordgo.ApplicationCommand) error {
+	if !commandNamePattern.MatchString(command.Name) {
+		return fmt.Errorf("name must be 1-32 lowercase letters, digits, '-' or '_'")
+	}
+	if err := validateDescription(command.Description); err != nil {
+		return err
+	}
+	return validateOptions(command.Options)
+}
+
+func validateOptions(options []*discordgo.ApplicationCommandOption) error {
+	if len(options) > 25 {
+		return fmt.Errorf("%d options, at most 25 are allowed", len(options))
+	}
+
+	optional := false
+	for _, option := range options {
+		if !commandNamePattern.MatchString(option.Name) {
+			return fmt.Errorf("option '%s': name must be 1-32 lowercase letters, digits, '-' or '_'", option.Name)
+		}
+		if err := validateDescription(option.Description); err != nil {
+			return fmt.Errorf("option '%s': %w", option.Name, err)
+		}
+		if len(option.Choices) > 25 {
+			return fmt.Errorf("option '%s': %d choices, at most 25 are allowed", option.Name, len(option.Choices))
+		}
+
+		switch option.Type {
+		case discordgo.ApplicationCommandOptionSubCommand, discordgo.ApplicationCommandOptionSubCommandGroup:
+			if err := validateOptions(option.Options); err != nil {
+				return fmt.Errorf("option '%s': %w", option.Name, err)
+			}
+		default:
+			if option.Required && optional {
+				return fmt.Errorf("required option '%s' is listed after an optional option", option.Name)
+			}
+			optional = optional || !option.Required
+		}
+	}
+	return nil
+}
+
+func validateDescription(description string) error {
+	length := utf8.RuneCountInString(description)
+	if length < 1 || length > 100 {
+		return fmt.Errorf("description must be 1-100 characters, got %d", length)
+	}
+	return nil
+}
+
+func diffCommands(wanted, registered []*discordgo.ApplicationCommand) commandSyncPlan {
+	plan := commandSyncPlan{}
+
+	existing := make(map[string]*discordgo.ApplicationCommand)
+	for _, command := range registered {
+		existing[command.Name] = command
+	}
+
+	for _, command := range wanted {
+		current, ok := existing[command.Name]
+		switch {
+		case !ok:
+			plan.Create = append(plan.Create, command.Name)
+		case commandChanged(command, current):
+			plan.Update = append(plan.Update, command.Name)
+		default:
+			plan.Unchanged++
+		}
+		delete(existing, command.Name)
+	}
+
+	for name := range existing {
+		plan.Delete = append(plan.Delete, name)
+	}
+
+	sort.Strings(plan.Create)
+	sort.Strings(plan.Update)
+	sort.Strings(plan.Delete)
+	return plan
+}
+
+// commandChanged compares the parts of the commands set by the cogs.
+func commandChanged(wanted, registered *discordgo.ApplicationCommand) bool {
+	if wanted.Description != registered.Description {
+		return true
+	}
+	return optionsKey(wanted.Options) != optionsKey(registered.Options)
+}
+
+// optionsKey serializes the options with empty lists and missing lists treated the same.
+func optionsKey(options []*discordgo.ApplicationCommandOption) string {
+	normalized := make([]map[string]interface{}, 0, len(options))
+	for _, option := range options {
+		choices := []string{}
+		for _, choice := range option.Choices {
+			value, _ := json.Marshal(choice.Value)
+			choices = append(choices, choice.Name+"="+string(value))
+		}
+		channelTypes := []int{}
+		for _, channelType := range option.ChannelTypes {
+			channelTypes = append(channelTypes, int(channelType))
+		}
+
+		normalized = append(normalized, map[string]interface{}{
+			"type":          option.Type,
+			"name":          option.Name,
+			"description":   option.Description,
+			"required":      option.Required,
+			"choices":       strings.Join(choices, ","),
+			"channel_types": channelTypes,
+			"min_value":     option.MinValue,
+			"max_value":     option.MaxValue,
+			"options":       optionsKey(option.Options),
+		})
+	}
+
+	key, _ := json.Marshal(normalized)
+	return string(key)
+}
+
+func scopeName(guildID string) string {
+	if guildID == "" {
+		return "global commands"
+	}
+	return "guild " + guildID
+}
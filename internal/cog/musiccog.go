@@ -6,6 +6,7 @@ import (
 	"phoenixbot/internal/config"
 	"phoenixbot/internal/discord"
 	"phoenixbot/internal/music"
+	"phoenixbot/internal/storage"
 	"phoenixbot/internal/util"
 	"sync"
 	"time"
@@ -15,7 +16,15 @@ import (
 	"github.com/kkdai/youtube/v2"
 )
 
+const (
+	defaultLyricsApi = "https://lrclib.net/api"
+	lyricsPageSize   = 2000
+	defaultLinkLimit = 25 // Tracks queued from one streaming link
+	musicStateFile   = "music.json"
+)
+
 type Song struct {
+	ID       string // Youtube video id
 	Title    string
 	URL      string
 	Duration string
@@ -31,16 +40,49 @@ type MusicGuildConfig struct {
 		Error   string `json:"Error"`
 	} `json:"Embed_colors"`
 
+	Require_same_channel bool              `json:"Require_same_channel"` // Reject requests and controls from outside the bot's voice channel
+	Dj_roles             map[string]string `json:"Dj_roles"`             // Roles allowed to move the bot (name and ID)
+
+	Multiple_sessions bool                 `json:"Multiple_sessions"` // Use Music_channels instead of Music_channel
+	Music_channels    []MusicChannelConfig `json:"Music_channels"`
+
+	Max_link_tracks int `json:"Max_link_tracks"` // Tracks queued from one Spotify or Apple Music link, 0 uses the default
+
+	Sessions        map[string]*MusicSession // Maps music channel id to its session
+	ActiveSession   *MusicSession            // Session currently using the voice connection
+	VoiceConnection *discordgo.VoiceConnection
+}
+
+type MusicChannelConfig struct {
+	Channel       string `json:"Channel"`       // Channel where songs are requested
+	Voice_channel string `json:"Voice_channel"` // Voice channel the session plays in
+}
+
+// MusicSession is the queue and controller embed of a single music channel.
+// Discord only allows one voice connection per guild, so sessions take turns on it.
+type MusicSession struct {
+	Channel      string
+	VoiceChannel string // Empty follows the voice channel of the requester
+
 	Queue            []Song
 	CurrentlyPlaying *Song
 	IsPlaying        bool
 
-	MessageId       string
-	VoiceConnection *discordgo.VoiceConnection
+	MessageId string
+
+	stop    chan struct{} // Closed to stop the song currently streamed by the session
+	stopped chan struct{} // Closed once the streamed song stopped
+}
+
+// MusicState is persisted between restarts so controller embeds can be reused.
+type MusicState struct {
+	Controllers map[string]string `json:"Controllers"` // Maps music channel id to controller message id
 }
 
 type MusicConfig struct {
-	Guilds map[string]*MusicGuildConfig `json:"Guilds"`
+	Guilds       map[string]*MusicGuildConfig `json:"Guilds"`
+	Lyrics_api   string                       `json:"Lyrics_api"`
+	Metadata_api string                       `json:"Metadata_api"`
 }
 
 type MusicCog struct {
@@ -49,15 +91,40 @@ type MusicCog struct {
 	ConfigName string
 
 	Config     *MusicConfig
+	State      MusicState
 	MusicMutex sync.RWMutex
 
 	Youtube *youtube.Client
+
+	Lyrics      music.LyricsProvider
+	LyricsCache sync.Map // Maps video id to lyrics
+
+	Metadata music.MetadataResolver
 }
 
 func (m *MusicCog) Name() string {
 	return "MusicCog"
 }
 
+func (m *MusicCog) CommandsLoaded() bool {
+	return m.Config != nil
+}
+
+// Commands returns the music commands of guilds with music enabled.
+func (m *MusicCog) Commands(guildID string) []*discordgo.ApplicationCommand {
+	m.MusicMutex.RLock()
+	defer m.MusicMutex.RUnlock()
+
+	conf, ok := m.Config.Guilds[guildID]
+	if !ok || !conf.Enabled || !config.IsGuildEnabled(guildID) {
+		return nil
+	}
+	return []*discordgo.ApplicationCommand{{
+		Name:        "lyrics",
+		Description: "Show the lyrics of the currently playing song",
+	}}
+}
+
 func (m *MusicCog) Init() error {
 	var musicConfig MusicConfig
 	if err := config.LoadConfig(m.ConfigName, &musicConfig); err != nil {
@@ -65,6 +132,25 @@ func (m *MusicCog) Init() error {
 	}
 	m.Config = &musicConfig
 
+	if err := storage.Load(musicStateFile, &m.State); err != nil {
+		config.Logger.Errorln(err)
+	}
+	if m.State.Controllers == nil {
+		m.State.Controllers = make(map[string]string)
+	}
+
+	if m.Lyrics == nil {
+		baseURL := m.Config.Lyrics_api
+		if baseURL == "" {
+			baseURL = defaultLyricsApi
+		}
+		m.Lyrics = &music.HTTPLyricsProvider{BaseURL: baseURL}
+	}
+
+	if m.Metadata == nil && m.Config.Metadata_api != "" {
+		m.Metadata = &music.HTTPMetadataResolver{BaseURL: m.Config.Metadata_api}
+	}
+
 	m.MusicMutex.Lock()
 	glds := m.Config.Guilds
 	m.MusicMutex.Unlock()
@@ -77,9 +163,14 @@ func (m *MusicCog) Init() error {
 			config.Logger.Infoln("Music feature disabled in config, on server ", guild)
 			continue
 		}
-		discord.ClearMessagesOnChannel(m.Session, mus.Music_channel, nil)
-
-		mus.Queue = make([]Song, 0)
+		mus.Sessions = make(map[string]*MusicSession)
+		if mus.Multiple_sessions {
+			for _, ch := range mus.Music_channels {
+				mus.Sessions[ch.Channel] = &MusicSession{Channel: ch.Channel, VoiceChannel: ch.Voice_channel, Queue: make([]Song, 0)}
+			}
+		} else {
+			mus.Sessions[mus.Music_channel] = &MusicSession{Channel: mus.Music_channel, Queue: make([]Song, 0)}
+		}
 		config.Logger.Infoln()
 
 		m.MusicMutex.Lock()
@@ -96,7 +187,19 @@ func (m *MusicCog) Init() error {
 			if !mus.Enabled {
 				continue
 			}
-			m.updateMusicEmbed(m.Session, guild)
+			for _, session := range mus.Sessions {
+				m.MusicMutex.RLock()
+				messageID := m.State.Controllers[session.Channel]
+				m.MusicMutex.RUnlock()
+
+				// Reuse the controller from the previous run and remove only our own leftovers
+				if discord.ClearStaleOwnMessages(s, session.Channel, messageID) {
+					m.MusicMutex.Lock()
+					session.MessageId = messageID
+					m.MusicMutex.Unlock()
+				}
+				m.updateMusicEmbed(m.Session, guild, session)
+			}
 		}
 
 	})
@@ -141,18 +244,32 @@ func (m *MusicCog) getConfig(guildID string) *MusicGuildConfig {
 
 	return conf
 }
-func (m *MusicCog) handleMessage(s *discordgo.Session, msg *discordgo.MessageCreate) {
 
-	conf := m.getConfig(msg.GuildID)
+// getSession returns the music session of the channel, or nil if it isnt a music channel.
+func (m *MusicCog) getSession(guildID, channelID string) *MusicSession {
+	conf := m.getConfig(guildID)
 	if conf == nil {
+		return nil
+	}
+
+	m.MusicMutex.RLock()
+	defer m.MusicMutex.RUnlock()
+
+	return conf.Sessions[channelID]
+}
+
+func (m *MusicCog) handleMessage(s *discordgo.Session, msg *discordgo.MessageCreate) {
+
+	session := m.getSession(msg.GuildID, msg.ChannelID)
+	if session == nil {
 		return
 	}
 
-	if msg.Author.Bot || msg.ChannelID != conf.Music_channel {
+	if msg.Author.Bot {
 		return
 	}
 
-	defer s.ChannelMessageDelete(conf.Music_channel, msg.ID)
+	defer s.ChannelMessageDelete(session.Channel, msg.ID)
 
 	voiceState := discord.GetUserVoiceState(s, msg.GuildID, msg.Author.ID)
 	if voiceState == nil {
@@ -160,59 +277,324 @@ func (m *MusicCog) handleMessage(s *discordgo.Session, msg *discordgo.MessageCre
 		return
 	}
 
-	err := m.joinVoiceChannelIfNeeded(msg.GuildID, voiceState.ChannelID)
+	channelID, err := m.resolveVoiceChannel(msg.GuildID, session, msg.Member, voiceState.ChannelID)
+	if err != nil {
+		discord.SendReplyMessageTimed(m.Session, msg.ChannelID, msg.ID, fmt.Sprintf("Cannot add song: %v", err), time.Second*3)
+		return
+	}
+
+	// Resolve the request before joining, a bad request must not take over the connection
+	songs, tracks, err := m.requestedSongs(s, msg)
+	if err != nil {
+		discord.SendReplyMessageTimed(m.Session, msg.ChannelID, msg.ID, err.Error(), time.Second*2)
+		return
+	}
+
+	err = m.joinVoiceChannelIfNeeded(msg.GuildID, session, channelID)
 	if err != nil {
 		discord.SendReplyMessageTimed(m.Session, msg.ChannelID, msg.ID, fmt.Sprintf("Failed to join voice channel: %v", err), time.Second*2)
 		return
 	}
 
+	queued := 0
+	for _, song := range songs {
+		if err := m.queueSong(msg.GuildID, session, song); err != nil {
+			if queued == 0 {
+				discord.SendReplyMessageTimed(m.Session, msg.ChannelID, msg.ID, fmt.Sprintf("Cannot add song: %v", err), time.Second*2)
+				return
+			}
+			config.Logger.Infoln("Stopped queueing", msg.Content, err)
+			break
+		}
+		queued++
+	}
+
+	if queued < tracks || tracks > 1 {
+		discord.SendReplyMessageTimed(m.Session, msg.ChannelID, msg.ID, fmt.Sprintf("Queued %d of %d tracks.", queued, tracks), time.Second*3)
+	}
+}
+
+// requestedSongs returns the songs requested by the message and how many tracks were requested.
+// The returned errors are meant for the requester.
+func (m *MusicCog) requestedSongs(s *discordgo.Session, msg *discordgo.MessageCreate) ([]Song, int, error) {
+	if link := music.FindStreamingLink(msg.Content); link != "" {
+		return m.streamingLinkSongs(msg, link)
+	}
+
 	video, err := m.getYoutubeVideo(s, msg)
 	if err != nil {
 		config.Logger.Warnln(err)
-		discord.SendReplyMessageTimed(m.Session, msg.ChannelID, msg.ID, "Failed to fetch video. Please ensure the URL is valid.", time.Second*2)
-		return
+		return nil, 0, fmt.Errorf("Failed to fetch video. Please ensure the URL is valid.")
+	}
+	return []Song{newSong(video)}, 1, nil
+}
+
+// streamingLinkSongs matches the tracks of a Spotify or Apple Music link to youtube by search.
+// Only the first Max_link_tracks tracks are searched, the search progress is shown in a reply.
+func (m *MusicCog) streamingLinkSongs(msg *discordgo.MessageCreate, link string) ([]Song, int, error) {
+	if m.Metadata == nil {
+		return nil, 0, fmt.Errorf("Spotify and Apple Music links are not supported.")
+	}
+
+	tracks, err := m.Metadata.Resolve(link)
+	if err != nil {
+		config.Logger.Warnln("Failed to resolve", link, err)
+		return nil, 0, fmt.Errorf("Failed to resolve that link.")
+	}
+
+	limit := 0
+	if conf := m.getConfig(msg.GuildID); conf != nil {
+		limit = conf.Max_link_tracks
+	}
+	if limit <= 0 {
+		limit = defaultLinkLimit
+	}
+	search := tracks
+	if len(search) > limit {
+		search = search[:limit]
+	}
+
+	// Every search takes a while, so show how far it got
+	progress, err := m.Session.ChannelMessageSendReply(msg.ChannelID, fmt.Sprintf("Searching %d tracks on youtube...", len(search)), msg.Reference())
+	if err != nil {
+		config.Logger.Warnln("Failed to send search progress:", err)
+	} else {
+		defer m.Session.ChannelMessageDelete(msg.ChannelID, progress.ID)
+	}
+
+	songs := []Song{}
+	for i, track := range search {
+		if progress != nil && i > 0 && i%5 == 0 {
+			m.Session.ChannelMessageEdit(msg.ChannelID, progress.ID, fmt.Sprintf("Searching tracks on youtube... %d/%d", i, len(search)))
+		}
+
+		url, err := music.FindYouTubeVideo(track.String())
+		if err != nil {
+			config.Logger.Warnln("No youtube match for", track.String(), err)
+			continue
+		}
+
+		video, err := m.fetchYouTubeVideo(url)
+		if err != nil {
+			config.Logger.Warnln("Failed to fetch video for", track.String(), err)
+			continue
+		}
+
+		song := newSong(video)
+		song.Title = track.String()
+		songs = append(songs, song)
 	}
 
-	song := Song{
+	if len(songs) == 0 {
+		return nil, 0, fmt.Errorf("Couldnt find any track of that link on youtube.")
+	}
+	return songs, len(tracks), nil
+}
+
+func newSong(video *youtube.Video) Song {
+	return Song{
+		ID:       video.ID,
 		Title:    video.Title,
 		URL:      util.YoutubeIdToUrl(video.ID),
 		Duration: fmt.Sprintf("%02d:%02d", video.Duration/time.Minute, (video.Duration%time.Minute)/time.Second),
 	}
+}
+
+func (m *MusicCog) queueSong(guildID string, session *MusicSession, song Song) error {
+
+	conf := m.getConfig(guildID)
+	if conf == nil {
+		return fmt.Errorf("no config on musiccog for guild %s", guildID)
+	}
+
 	m.MusicMutex.Lock()
 
-	conf.Queue = append(conf.Queue, song)
+	if conf.Max_queue_size > 0 && len(session.Queue) >= conf.Max_queue_size {
+		m.MusicMutex.Unlock()
+		return fmt.Errorf("queue is full")
+	}
+	session.Queue = append(session.Queue, song)
 
 	m.MusicMutex.Unlock()
-	if !conf.IsPlaying {
-		m.startQueueWorker(msg.GuildID)
+	if !session.IsPlaying {
+		m.startQueueWorker(guildID, session)
 	}
+	return nil
 }
 
 func (m *MusicCog) fetchYouTubeVideo(url string) (*youtube.Video, error) {
 	return m.Youtube.GetVideo(url)
 }
 
-func (m *MusicCog) joinVoiceChannelIfNeeded(guildID, channelID string) error {
+func (m *MusicCog) isDJ(conf *MusicGuildConfig, member *discordgo.Member) bool {
+	return discord.MemberHasAnyRole(member, conf.Dj_roles)
+}
+
+// resolveVoiceChannel returns the voice channel a request from userChannelID should play in.
+// The bot only leaves an active session if the member is a DJ or nobody is listening anymore.
+func (m *MusicCog) resolveVoiceChannel(guildID string, session *MusicSession, member *discordgo.Member, userChannelID string) (string, error) {
+	conf := m.getConfig(guildID)
+	if conf == nil {
+		return "", fmt.Errorf("no config on musiccog for guild %s", guildID)
+	}
+
+	target := userChannelID
+	if session.VoiceChannel != "" {
+		if conf.Require_same_channel && userChannelID != session.VoiceChannel && !m.isDJ(conf, member) {
+			return "", fmt.Errorf("join <#%s> to add songs in this channel", session.VoiceChannel)
+		}
+		target = session.VoiceChannel
+	}
+
+	m.MusicMutex.RLock()
+	vc := conf.VoiceConnection
+	activeSession := conf.ActiveSession
+	active := activeSession != nil && activeSession.CurrentlyPlaying != nil
+	m.MusicMutex.RUnlock()
+
+	if vc == nil || !active || (activeSession == session && vc.ChannelID == target) {
+		return target, nil
+	}
+
+	if m.isDJ(conf, member) || discord.CountVoiceChannelUsers(m.Session, guildID, vc.ChannelID) == 0 {
+		return target, nil
+	}
+
+	if activeSession != session {
+		return "", fmt.Errorf("already playing for <#%s> in <#%s>", activeSession.Channel, vc.ChannelID)
+	}
+
+	if conf.Require_same_channel {
+		return "", fmt.Errorf("already playing in <#%s>, join that channel to add songs", vc.ChannelID)
+	}
+
+	// Queue into the running session without moving the bot
+	return vc.ChannelID, nil
+}
+
+// canControlPlayback reports whether the member may use the controller buttons of the session.
+func (m *MusicCog) canControlPlayback(guildID string, session *MusicSession, member *discordgo.Member) bool {
+	conf := m.getConfig(guildID)
+	if conf == nil {
+		return false
+	}
+
+	m.MusicMutex.RLock()
+	vc := conf.VoiceConnection
+	activeSession := conf.ActiveSession
+	m.MusicMutex.RUnlock()
+
+	// Controls of an idle session dont touch the voice connection
+	if activeSession != session {
+		return true
+	}
+
+	if !conf.Require_same_channel || vc == nil || m.isDJ(conf, member) {
+		return true
+	}
+
+	if member == nil || member.User == nil {
+		return false
+	}
+
+	voiceState := discord.GetUserVoiceState(m.Session, guildID, member.User.ID)
+	if voiceState != nil && voiceState.ChannelID == vc.ChannelID {
+		return true
+	}
+
+	return discord.CountVoiceChannelUsers(m.Session, guildID, vc.ChannelID) == 0
+}
+
+func (m *MusicCog) joinVoiceChannelIfNeeded(guildID string, session *MusicSession, channelID string) error {
 
 	conf := m.getConfig(guildID)
 	if conf == nil {
 		return fmt.Errorf("no config on musiccog for guild %s", guildID)
 	}
 
-	if conf.VoiceConnection != nil {
-		if conf.VoiceConnection.ChannelID == channelID {
-			return nil
+	m.MusicMutex.Lock()
+	previous := conf.ActiveSession
+	vc := conf.VoiceConnection
+	moving := vc != nil && vc.ChannelID != channelID
+	var stopped <-chan struct{}
+	if moving {
+		// The song streamed on the old connection is played again on the new one
+		if previous != nil {
+			if previous.CurrentlyPlaying != nil {
+				previous.Queue = append([]Song{*previous.CurrentlyPlaying}, previous.Queue...)
+				previous.CurrentlyPlaying = nil
+			}
+			stopped = stopStream(previous)
+		}
+		conf.VoiceConnection = nil
+	}
+	m.MusicMutex.Unlock()
+
+	// SendPCM blocks on a disconnected connection, so the stream has to end first
+	if stopped != nil {
+		<-stopped
+	}
+
+	if vc == nil || moving {
+		if moving {
+			vc.Disconnect()
+			time.Sleep(100 * time.Millisecond)
 		}
-		conf.VoiceConnection.Disconnect()
-		time.Sleep(100 * time.Millisecond)
+
+		newVC, err := m.Session.ChannelVoiceJoin(guildID, channelID, false, true)
+		if err != nil {
+			if moving && previous != nil {
+				m.rejoinVoiceChannel(guildID, previous, vc.ChannelID)
+			}
+			return fmt.Errorf("failed to join voice channel: %v", err)
+		}
+
+		m.MusicMutex.Lock()
+		conf.VoiceConnection = newVC
+		m.MusicMutex.Unlock()
+	}
+
+	// Session taking over the voice connection stops the previous one, only once the join worked
+	m.MusicMutex.Lock()
+	stopped = nil
+	if previous != nil && previous != session {
+		previous.Queue = nil
+		previous.CurrentlyPlaying = nil
+		previous.IsPlaying = false
+		stopped = stopStream(previous)
+	}
+	conf.ActiveSession = session
+	m.MusicMutex.Unlock()
+
+	if stopped != nil {
+		<-stopped
+	}
+	if previous != nil && previous != session {
+		m.updateMusicEmbed(m.Session, guildID, previous)
+	}
+
+	m.restartQueue(guildID, session)
+	return nil
+}
+
+// rejoinVoiceChannel moves the bot back to the channel of the session after a failed move.
+func (m *MusicCog) rejoinVoiceChannel(guildID string, session *MusicSession, channelID string) {
+	conf := m.getConfig(guildID)
+	if conf == nil {
+		return
 	}
 
 	vc, err := m.Session.ChannelVoiceJoin(guildID, channelID, false, true)
 	if err != nil {
-		return fmt.Errorf("failed to join voice channel: %v", err)
+		config.Logger.Warnln("Failed to rejoin voice channel", channelID, err)
+		return
 	}
+
+	m.MusicMutex.Lock()
 	conf.VoiceConnection = vc
-	return nil
+	m.MusicMutex.Unlock()
+
+	m.restartQueue(guildID, session)
 }
 
 func (m *MusicCog) handleInteraction(s *discordgo.Session, interaction *discordgo.InteractionCreate) {
@@ -223,23 +605,58 @@ func (m *MusicCog) handleInteraction(s *discordgo.Session, interaction *discordg
 		return
 	}
 
-	if interaction.ChannelID != conf.Music_channel {
+	if interaction.Type == discordgo.InteractionApplicationCommand {
+		if interaction.ApplicationCommandData().Name == "lyrics" {
+			session := m.getSession(gid, interaction.ChannelID)
+			if session == nil {
+				m.MusicMutex.RLock()
+				session = conf.ActiveSession
+				m.MusicMutex.RUnlock()
+			}
+			m.sendLyrics(s, interaction.Interaction, session)
+		}
+		return
+	}
+
+	if interaction.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+
+	session := m.getSession(gid, interaction.ChannelID)
+	if session == nil {
+		return
+	}
+
+	if interaction.MessageComponentData().CustomID == "phoenix_music_lyrics" {
+		m.sendLyrics(s, interaction.Interaction, session)
+		return
+	}
+
+	if !m.canControlPlayback(gid, session, interaction.Member) {
+		s.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "You must be in the same voice channel as the bot to use the controls.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
 		return
 	}
+
 	switch interaction.MessageComponentData().CustomID {
 	case "phoenix_music_play":
-		m.resumePlayback(gid)
+		m.resumePlayback(gid, session)
 	case "phoenix_music_pause":
-		m.pausePlayback(gid)
+		m.pausePlayback(gid, session)
 	case "phoenix_music_skip":
-		m.skipSong(gid)
+		m.skipSong(gid, session)
 	case "phoenix_music_disconnect":
-		m.disconnectFromVoice(gid)
+		m.disconnectFromVoice(gid, session)
 	}
-	m.updateMusicEmbed(s, gid)
+	m.updateMusicEmbed(s, gid, session)
 }
 
-func (m *MusicCog) startQueueWorker(guildID string) {
+func (m *MusicCog) startQueueWorker(guildID string, session *MusicSession) {
 
 	conf := m.getConfig(guildID)
 	if conf == nil {
@@ -249,22 +666,29 @@ func (m *MusicCog) startQueueWorker(guildID string) {
 	go func() {
 		for {
 			m.MusicMutex.Lock()
-			if len(conf.Queue) == 0 {
-				conf.IsPlaying = false
-				conf.CurrentlyPlaying = nil
+			// Moving or taking over the connection stops the worker, the queue is kept
+			if conf.ActiveSession != session || conf.VoiceConnection == nil {
+				session.IsPlaying = false
+				m.MusicMutex.Unlock()
+				m.updateMusicEmbed(m.Session, guildID, session)
+				break
+			}
+			if len(session.Queue) == 0 {
+				session.IsPlaying = false
+				session.CurrentlyPlaying = nil
 				m.MusicMutex.Unlock()
-				m.updateMusicEmbed(m.Session, guildID)
+				m.updateMusicEmbed(m.Session, guildID, session)
 				break
 			}
 
-			conf.CurrentlyPlaying = &conf.Queue[0]
-			conf.Queue = conf.Queue[1:]
-			conf.IsPlaying = true
+			session.CurrentlyPlaying = &session.Queue[0]
+			session.Queue = session.Queue[1:]
+			session.IsPlaying = true
 			m.MusicMutex.Unlock()
 
-			m.updateMusicEmbed(m.Session, guildID)
+			m.updateMusicEmbed(m.Session, guildID, session)
 
-			err := m.streamCurrentSong(guildID)
+			err := m.streamCurrentSong(guildID, session)
 			if err != nil {
 				log.Println("Error streaming song:", err)
 			}
@@ -272,7 +696,7 @@ func (m *MusicCog) startQueueWorker(guildID string) {
 	}()
 }
 
-func (m *MusicCog) streamCurrentSong(guildID string) error {
+func (m *MusicCog) streamCurrentSong(guildID string, session *MusicSession) error {
 	config.Logger.Debugln("Starting to stream current song")
 	tconf := m.getConfig(guildID)
 	if tconf == nil {
@@ -281,14 +705,29 @@ func (m *MusicCog) streamCurrentSong(guildID string) error {
 
 	conf := tconf
 	m.MusicMutex.Lock()
-	defer m.MusicMutex.Unlock()
 
-	if conf.CurrentlyPlaying == nil || conf.VoiceConnection == nil {
+	if session.CurrentlyPlaying == nil || conf.VoiceConnection == nil || conf.ActiveSession != session {
+		m.MusicMutex.Unlock()
 		return nil
 	}
 
-	config.Logger.Debugln("Fetching stream for:", conf.CurrentlyPlaying.URL)
-	u := conf.CurrentlyPlaying.URL
+	// Dont hold the lock while streaming, other sessions still need it
+	vc := conf.VoiceConnection
+	u := session.CurrentlyPlaying.URL
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	session.stop, session.stopped = stop, stopped
+	m.MusicMutex.Unlock()
+
+	defer func() {
+		m.MusicMutex.Lock()
+		if session.stop == stop {
+			session.stop, session.stopped = nil, nil
+		}
+		m.MusicMutex.Unlock()
+		close(stopped)
+	}()
+
+	config.Logger.Debugln("Fetching stream for:", u)
 	stream, err := music.GetYouTubeStream(u)
 	if err != nil {
 		config.Logger.Errorln("Failed to get stream:", err)
@@ -297,46 +736,88 @@ func (m *MusicCog) streamCurrentSong(guildID string) error {
 	defer stream.Close()
 
 	config.Logger.Debugln("Stream obtained, starting to decode PCM")
-	pcmChan := make(chan []int16, 1024)
+	decoded := make(chan []int16, 1024)
 	go func() {
-		if err := music.DecodeAudioToPCM(stream, pcmChan); err != nil {
+		if err := music.DecodeAudioToPCM(stream, decoded); err != nil {
 			config.Logger.Errorln("Error decoding audio: %v", err)
 		}
+		close(decoded)
+	}()
+
+	// Closing pcmChan ends SendPCM, the decoder is drained so it can exit once the stream is closed
+	pcmChan := make(chan []int16)
+	go func() {
+		for samples := range decoded {
+			select {
+			case pcmChan <- samples:
+			case <-stop:
+				close(pcmChan)
+				for range decoded {
+				}
+				return
+			}
+		}
 		close(pcmChan)
 	}()
 
-	dgvoice.SendPCM(conf.VoiceConnection, pcmChan)
+	dgvoice.SendPCM(vc, pcmChan)
 
 	return nil
 }
 
-func (m *MusicCog) pausePlayback(guildID string) {
+// stopStream stops the song streamed by the session and returns a channel closed once it stopped,
+// or nil if nothing is streamed. The caller must hold MusicMutex and wait without it.
+func stopStream(session *MusicSession) <-chan struct{} {
+	if session.stop == nil {
+		return nil
+	}
+	stopped := session.stopped
+	close(session.stop)
+	session.stop, session.stopped = nil, nil
+	return stopped
+}
+
+// restartQueue starts playing the queue of the session again if its worker stopped.
+func (m *MusicCog) restartQueue(guildID string, session *MusicSession) {
+	m.MusicMutex.Lock()
+	start := !session.IsPlaying && len(session.Queue) > 0
+	if start {
+		session.IsPlaying = true
+	}
+	m.MusicMutex.Unlock()
+
+	if start {
+		m.startQueueWorker(guildID, session)
+	}
+}
+
+func (m *MusicCog) pausePlayback(guildID string, session *MusicSession) {
 
 	conf := m.getConfig(guildID)
 	if conf == nil {
 		config.Logger.Warnln("no config on musiccog for guild ", guildID)
 		return
 	}
-	conf.IsPlaying = false
+	session.IsPlaying = false
 }
 
-func (m *MusicCog) resumePlayback(guildID string) {
+func (m *MusicCog) resumePlayback(guildID string, session *MusicSession) {
 	conf := m.getConfig(guildID)
 	if conf == nil {
 		config.Logger.Warnln("no config on musiccog for guild ", guildID)
 		return
 	}
-	if conf.CurrentlyPlaying != nil && !conf.IsPlaying {
-		conf.IsPlaying = true
-		m.startQueueWorker(guildID)
+	if session.CurrentlyPlaying != nil && !session.IsPlaying {
+		session.IsPlaying = true
+		m.startQueueWorker(guildID, session)
 	}
 }
 
-func (m *MusicCog) skipSong(guildID string) {
-	m.startQueueWorker(guildID)
+func (m *MusicCog) skipSong(guildID string, session *MusicSession) {
+	m.startQueueWorker(guildID, session)
 }
 
-func (m *MusicCog) disconnectFromVoice(guildID string) {
+func (m *MusicCog) disconnectFromVoice(guildID string, session *MusicSession) {
 
 	conf := m.getConfig(guildID)
 	if conf == nil {
@@ -344,17 +825,26 @@ func (m *MusicCog) disconnectFromVoice(guildID string) {
 		return
 	}
 	m.MusicMutex.Lock()
-	defer m.MusicMutex.Unlock()
-	if conf.VoiceConnection != nil {
-		conf.VoiceConnection.Disconnect()
-		conf.VoiceConnection = nil
-		conf.IsPlaying = false
-		conf.CurrentlyPlaying = nil
-		conf.Queue = nil
+	vc := conf.VoiceConnection
+	if vc == nil || conf.ActiveSession != session {
+		m.MusicMutex.Unlock()
+		return
+	}
+	stopped := stopStream(session)
+	conf.VoiceConnection = nil
+	conf.ActiveSession = nil
+	session.IsPlaying = false
+	session.CurrentlyPlaying = nil
+	session.Queue = nil
+	m.MusicMutex.Unlock()
+
+	if stopped != nil {
+		<-stopped
 	}
+	vc.Disconnect()
 }
 
-func (m *MusicCog) updateMusicEmbed(s *discordgo.Session, guildID string) {
+func (m *MusicCog) updateMusicEmbed(s *discordgo.Session, guildID string, session *MusicSession) {
 
 	conf := m.getConfig(guildID)
 	if conf == nil {
@@ -363,16 +853,16 @@ func (m *MusicCog) updateMusicEmbed(s *discordgo.Session, guildID string) {
 	}
 
 	var color int
-	if conf.IsPlaying {
+	if session.IsPlaying {
 		color = 0x00FF00
 	} else {
 		color = 0xFFFF00
 	}
 
 	description := "No songs currently playing."
-	if conf.CurrentlyPlaying != nil {
-		description = fmt.Sprintf("**Now Playing:** [%s](%s) (%s)\n\n**Queue:**\n", conf.CurrentlyPlaying.Title, conf.CurrentlyPlaying.URL, conf.CurrentlyPlaying.Duration)
-		for i, song := range conf.Queue {
+	if session.CurrentlyPlaying != nil {
+		description = fmt.Sprintf("**Now Playing:** [%s](%s) (%s)\n\n**Queue:**\n", session.CurrentlyPlaying.Title, session.CurrentlyPlaying.URL, session.CurrentlyPlaying.Duration)
+		for i, song := range session.Queue {
 			description += fmt.Sprintf("%d. [%s](%s) (%s)\n", i+1, song.Title, song.URL, song.Duration)
 			if i >= 4 {
 				description += "...and more\n"
@@ -381,6 +871,10 @@ func (m *MusicCog) updateMusicEmbed(s *discordgo.Session, guildID string) {
 		}
 	}
 
+	if session.VoiceChannel != "" {
+		description = fmt.Sprintf("🔊 <#%s>\n\n%s", session.VoiceChannel, description)
+	}
+
 	embed := &discordgo.MessageEmbed{
 		Title:       "Now Playing",
 		Description: description,
@@ -391,15 +885,16 @@ func (m *MusicCog) updateMusicEmbed(s *discordgo.Session, guildID string) {
 		discordgo.Button{Label: "▶️", CustomID: "phoenix_music_play", Style: discordgo.SuccessButton},
 		discordgo.Button{Label: "⏸️", CustomID: "phoenix_music_pause", Style: discordgo.SecondaryButton},
 		discordgo.Button{Label: "⏭️", CustomID: "phoenix_music_skip", Style: discordgo.SecondaryButton},
+		discordgo.Button{Label: "📜", CustomID: "phoenix_music_lyrics", Style: discordgo.SecondaryButton},
 		discordgo.Button{Label: "Disconnect", CustomID: "phoenix_music_disconnect", Style: discordgo.DangerButton},
 	}
 
 	m.MusicMutex.Lock()
-	msgID := conf.MessageId
+	msgID := session.MessageId
 	m.MusicMutex.Unlock()
 
 	if msgID == "" {
-		msg, err := s.ChannelMessageSendComplex(conf.Music_channel, &discordgo.MessageSend{
+		msg, err := s.ChannelMessageSendComplex(session.Channel, &discordgo.MessageSend{
 			Embed: embed,
 			Components: []discordgo.MessageComponent{
 				discordgo.ActionsRow{Components: buttons},
@@ -407,17 +902,113 @@ func (m *MusicCog) updateMusicEmbed(s *discordgo.Session, guildID string) {
 		})
 		if err == nil {
 			m.MusicMutex.Lock()
-			conf.MessageId = msg.ID
+			session.MessageId = msg.ID
+			m.State.Controllers[session.Channel] = msg.ID
+			m.saveState()
 			m.MusicMutex.Unlock()
 		} else {
 			config.Logger.Errorln(err)
 		}
 	} else {
-		s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		_, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
 			Embed:      embed,
-			ID:         conf.MessageId,
-			Channel:    conf.Music_channel,
+			ID:         msgID,
+			Channel:    session.Channel,
 			Components: &[]discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}},
 		})
+		if discord.IsNotFound(err) {
+			// Controller was deleted by someone, post a new one
+			m.MusicMutex.Lock()
+			session.MessageId = ""
+			m.MusicMutex.Unlock()
+			m.updateMusicEmbed(s, guildID, session)
+		} else if err != nil {
+			config.Logger.Errorln(err)
+		}
+	}
+}
+
+// saveState persists the music state, MusicMutex must be held by the caller.
+func (m *MusicCog) saveState() {
+	if err := storage.Save(musicStateFile, &m.State); err != nil {
+		config.Logger.Errorln("Failed to save music state:", err)
+	}
+}
+
+func (m *MusicCog) getLyrics(song *Song) (string, error) {
+	if lyrics, ok := m.LyricsCache.Load(song.ID); ok {
+		return lyrics.(string), nil
+	}
+
+	lyrics, err := m.Lyrics.FindLyrics(song.Title)
+	if err != nil {
+		return "", err
+	}
+
+	m.LyricsCache.Store(song.ID, lyrics)
+	return lyrics, nil
+}
+
+func (m *MusicCog) sendLyrics(s *discordgo.Session, interaction *discordgo.Interaction, session *MusicSession) {
+
+	m.MusicMutex.RLock()
+	var song *Song
+	if session != nil && session.CurrentlyPlaying != nil {
+		current := *session.CurrentlyPlaying
+		song = &current
+	}
+	m.MusicMutex.RUnlock()
+
+	if song == nil {
+		s.InteractionRespond(interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "No songs currently playing.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
+
+	// Lyrics api can take longer than the interaction response window
+	err := s.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+	if err != nil {
+		config.Logger.Errorln("Failed to defer lyrics response:", err)
+		return
+	}
+
+	lyrics, err := m.getLyrics(song)
+	if err != nil {
+		config.Logger.Warnln("Failed to get lyrics for", song.Title, err)
+		s.FollowupMessageCreate(interaction, false, &discordgo.WebhookParams{
+			Content: fmt.Sprintf("Couldnt find lyrics for %s.", song.Title),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+		return
+	}
+
+	pages := util.SplitText(lyrics, lyricsPageSize)
+	for i, page := range pages {
+		embed := &discordgo.MessageEmbed{
+			Title:       song.Title,
+			URL:         song.URL,
+			Description: page,
+			Color:       0x00AAFF,
+		}
+		if len(pages) > 1 {
+			embed.Footer = &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Page %d/%d", i+1, len(pages))}
+		}
+
+		_, err := s.FollowupMessageCreate(interaction, false, &discordgo.WebhookParams{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		})
+		if err != nil {
+			config.Logger.Errorln("Failed to send lyrics page:", err)
+			return
+		}
 	}
 }
@@ -0,0 +1,191 @@
+package cog
+
+import (
+	"fmt"
+	"phoenixbot/internal/discord"
+	"sort"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type TicketBlacklistEntry struct {
+	Reason  string    `json:"Reason"`
+	AddedBy string    `json:"AddedBy"`
+	AddedAt time.Time `json:"AddedAt"`
+}
+
+// Denial messages used when the config leaves them empty
+const (
+	defaultBlacklisted   = "You are not allowed to open tickets. Reason: {reason}"
+	defaultAccountTooNew = "Your account has to be at least {days} days old to open a ticket, try again {time}."
+	defaultMemberTooNew  = "You have to be a member of the server for at least {days} days to open a ticket, try again {time}."
+	defaultOnCooldown    = "You can open a new ticket {time}."
+)
+
+// ticketDenial reports whether the user cant open a ticket and returns the message explaining why.
+// The reason is added to the placeholders of the user.
+func (m *TicketCog) ticketDenial(conf *TicketGuildConfig, guildID string, user *discordgo.User, member *discordgo.Member, values discord.TemplateValues) (bool, string) {
+	m.StateMutex.Lock()
+	entry, blacklisted := m.State.Blacklist[guildID][user.ID]
+	until, onCooldown := m.State.Cooldowns[guildID][user.ID]
+	m.StateMutex.Unlock()
+
+	if blacklisted {
+		reason := entry.Reason
+		if reason == "" {
+			reason = "no reason given"
+		}
+		values["reason"] = reason
+		return true, denialMessage(conf.Messages.Blacklisted, defaultBlacklisted, values)
+	}
+
+	if conf.MinAccountAge > 0 {
+		created, err := discordgo.SnowflakeTimestamp(user.ID)
+		allowedAt := created.Add(time.Duration(conf.MinAccountAge) * 24 * time.Hour)
+		if err == nil && time.Now().Before(allowedAt) {
+			setAgeValues(values, conf.MinAccountAge, allowedAt)
+			return true, denialMessage(conf.Messages.AccountTooNew, defaultAccountTooNew, values)
+		}
+	}
+
+	if conf.MinMemberAge > 0 && member != nil {
+		allowedAt := member.JoinedAt.Add(time.Duration(conf.MinMemberAge) * 24 * time.Hour)
+		if time.Now().Before(allowedAt) {
+			setAgeValues(values, conf.MinMemberAge, allowedAt)
+			return true, denialMessage(conf.Messages.MemberTooNew, defaultMemberTooNew, values)
+		}
+	}
+
+	if onCooldown && time.Now().Before(until) {
+		values["time"] = fmt.Sprintf("<t:%d:R>", until.Unix())
+		return true, denialMessage(conf.Messages.OnCooldown, defaultOnCooldown, values)
+	}
+	return false, ""
+}
+
+func setAgeValues(values discord.TemplateValues, days int, allowedAt time.Time) {
+	values["days"] = fmt.Sprint(days)
+	values["time"] = fmt.Sprintf("<t:%d:R>", allowedAt.Unix())
+}
+
+// denialMessage fills the configured message, or the fallback if none is configured.
+func denialMessage(message, fallback string, values discord.TemplateValues) string {
+	if message == "" {
+		message = fallback
+	}
+	return discord.ApplyTemplate(message, values)
+}
+
+// setCooldown stops the user from opening tickets until the given time, a longer existing cooldown is kept.
+func (m *TicketCog) setCooldown(guildID, userID string, until time.Time) {
+	m.StateMutex.Lock()
+	defer m.StateMutex.Unlock()
+
+	cooldowns, ok := m.State.Cooldowns[guildID]
+	if !ok {
+		cooldowns = make(map[string]time.Time)
+		m.State.Cooldowns[guildID] = cooldowns
+	}
+	if current, ok := cooldowns[userID]; ok && current.After(until) {
+		return
+	}
+
+	// Drop expired cooldowns so the state doesnt grow forever
+	for id, expires := range cooldowns {
+		if time.Now().After(expires) {
+			delete(cooldowns, id)
+		}
+	}
+
+	cooldowns[userID] = until
+	m.saveState()
+}
+
+func (m *TicketCog) handleBlacklistCommand(session *discordgo.Session, interaction *discordgo.Interaction, group *discordgo.ApplicationCommandInteractionDataOption) {
+
+	conf, ok := m.Config.Guilds[interaction.GuildID]
+	if !ok || len(group.Options) == 0 {
+		return
+	}
+
+	if !conf.isStaff(interaction.Member) {
+		sendNoPermission(session, interaction, conf)
+		return
+	}
+
+	subcommand := group.Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, option := range subcommand.Options {
+		options[option.Name] = option
+	}
+
+	switch subcommand.Name {
+	case "add":
+		user := options["user"].UserValue(nil)
+		reason := ""
+		if option, ok := options["reason"]; ok {
+			reason = option.StringValue()
+		}
+
+		m.StateMutex.Lock()
+		if m.State.Blacklist[interaction.GuildID] == nil {
+			m.State.Blacklist[interaction.GuildID] = make(map[string]*TicketBlacklistEntry)
+		}
+		m.State.Blacklist[interaction.GuildID][user.ID] = &TicketBlacklistEntry{
+			Reason:  reason,
+			AddedBy: interaction.Member.User.ID,
+			AddedAt: time.Now(),
+		}
+		m.saveState()
+		m.StateMutex.Unlock()
+
+		discord.SendEphemeralResponse(session, interaction, fmt.Sprintf("<@%s> can no longer open tickets.", user.ID))
+
+	case "remove":
+		user := options["user"].UserValue(nil)
+
+		m.StateMutex.Lock()
+		_, found := m.State.Blacklist[interaction.GuildID][user.ID]
+		if found {
+			delete(m.State.Blacklist[interaction.GuildID], user.ID)
+			m.saveState()
+		}
+		m.StateMutex.Unlock()
+
+		if !found {
+			discord.SendEphemeralResponse(session, interaction, fmt.Sprintf("<@%s> isnt blacklisted.", user.ID))
+			return
+		}
+		discord.SendEphemeralResponse(session, interaction, fmt.Sprintf("<@%s> can open tickets again.", user.ID))
+
+	case "list":
+		m.StateMutex.Lock()
+		lines := []string{}
+		for userID, entry := range m.State.Blacklist[interaction.GuildID] {
+			line := fmt.Sprintf("<@%s> by <@%s> <t:%d:R>", userID, entry.AddedBy, entry.AddedAt.Unix())
+			if entry.Reason != "" {
+				line += ": " + entry.Reason
+			}
+			lines = append(lines, line)
+		}
+		m.StateMutex.Unlock()
+
+		if len(lines) == 0 {
+			discord.SendEphemeralResponse(session, interaction, "Nobody is blacklisted.")
+			return
+		}
+		sort.Strings(lines)
+
+		// Message content is limited to 2000 characters
+		content := ""
+		for i, line := range lines {
+			if len(content)+len(line) > 1900 {
+				content += fmt.Sprintf("...and %d more", len(lines)-i)
+				break
+			}
+			content += line + "\n"
+		}
+		discord.SendEphemeralResponse(session, interaction, content)
+	}
+}
@@ -0,0 +1,78 @@
+package cog
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	TicketModeThread  = "thread"
+	TicketModeChannel = "channel"
+)
+
+// Permissions of the owner, staff and added members in ticket channels
+const ticketMemberPermissions = discordgo.PermissionViewChannel |
+	discordgo.PermissionSendMessages |
+	discordgo.PermissionReadMessageHistory |
+	discordgo.PermissionAttachFiles |
+	discordgo.PermissionEmbedLinks
+
+// Permissions of the owner once the ticket is locked, they can still read it
+const ticketReadPermissions = discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory
+
+// createTicketChannel creates a text channel only the owner, staff and the bot can see.
+func (m *TicketCog) createTicketChannel(session *discordgo.Session, conf *TicketGuildConfig, category *TicketCategory, guildID, userID, name string) (*discordgo.Channel, error) {
+	overwrites := []*discordgo.PermissionOverwrite{
+		// The everyone role has the id of the guild
+		{ID: guildID, Type: discordgo.PermissionOverwriteTypeRole, Deny: discordgo.PermissionViewChannel},
+		{ID: userID, Type: discordgo.PermissionOverwriteTypeMember, Allow: ticketMemberPermissions},
+		{ID: session.State.User.ID, Type: discordgo.PermissionOverwriteTypeMember, Allow: ticketMemberPermissions | discordgo.PermissionManageChannels},
+	}
+	for _, roleID := range conf.staffRoles(category) {
+		overwrites = append(overwrites, &discordgo.PermissionOverwrite{ID: roleID, Type: discordgo.PermissionOverwriteTypeRole, Allow: ticketMemberPermissions})
+	}
+
+	return session.GuildChannelCreateComplex(guildID, discordgo.GuildChannelCreateData{
+		Name:                 name,
+		Type:                 discordgo.ChannelTypeGuildText,
+		ParentID:             conf.ChannelCategory,
+		PermissionOverwrites: overwrites,
+	})
+}
+
+// setTicketLocked archives and locks a ticket thread, or stops the owner from writing in a ticket channel.
+// An empty name keeps the current name.
+func setTicketLocked(session *discordgo.Session, ticket Ticket, locked bool, name string) error {
+	if ticket.Mode != TicketModeChannel {
+		archived := locked
+		_, err := session.ChannelEditComplex(ticket.ChannelID, &discordgo.ChannelEdit{Name: name, Archived: &archived, Locked: &locked})
+		return err
+	}
+
+	if name != "" {
+		if _, err := session.ChannelEditComplex(ticket.ChannelID, &discordgo.ChannelEdit{Name: name}); err != nil {
+			return err
+		}
+	}
+
+	allow, deny := int64(ticketMemberPermissions), int64(0)
+	if locked {
+		allow, deny = ticketReadPermissions, ticketMemberPermissions&^ticketReadPermissions
+	}
+	return session.ChannelPermissionSet(ticket.ChannelID, ticket.UserID, discordgo.PermissionOverwriteTypeMember, allow, deny)
+}
+
+// addTicketMember gives the user access to the ticket.
+func addTicketMember(session *discordgo.Session, ticket Ticket, userID string) error {
+	if ticket.Mode == TicketModeChannel {
+		return session.ChannelPermissionSet(ticket.ChannelID, userID, discordgo.PermissionOverwriteTypeMember, ticketMemberPermissions, 0)
+	}
+	return session.ThreadMemberAdd(ticket.ChannelID, userID)
+}
+
+// removeTicketMember takes away the access of the user to the ticket.
+func removeTicketMember(session *discordgo.Session, ticket Ticket, userID string) error {
+	if ticket.Mode == TicketModeChannel {
+		return session.ChannelPermissionDelete(ticket.ChannelID, userID)
+	}
+	return session.ThreadMemberRemove(ticket.ChannelID, userID)
+}
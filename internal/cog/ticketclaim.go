@@ -0,0 +1,267 @@
+package cog
+
+import (
+	"fmt"
+	"phoenixbot/internal/config"
+	"phoenixbot/internal/discord"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ticketInfoEmbed shows the owner, type and claimer of a ticket on its opening message.
+func ticketInfoEmbed(category *TicketCategory, ticket Ticket) *discordgo.MessageEmbed {
+	label := ticket.Category
+	if category != nil {
+		label = category.Label
+	}
+
+	claimedBy := "Unclaimed"
+	if ticket.ClaimedBy != "" {
+		claimedBy = fmt.Sprintf("<@%s> <t:%d:R>", ticket.ClaimedBy, ticket.ClaimedAt.Unix())
+	}
+
+	return &discordgo.MessageEmbed{
+		Title: "Ticket info",
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Opened by", Value: fmt.Sprintf("<@%s>", ticket.UserID), Inline: true},
+			{Name: "Type", Value: label, Inline: true},
+			{Name: "Claimed by", Value: claimedBy, Inline: true},
+		},
+	}
+}
+
+// refreshTicketInfo updates the info embed on the opening message of the ticket.
+func (m *TicketCog) refreshTicketInfo(session *discordgo.Session, channelID string) {
+	ticket, ok := m.getTicket(channelID)
+	if !ok || ticket.MessageID == "" {
+		return
+	}
+	conf, ok := m.Config.Guilds[ticket.GuildID]
+	if !ok {
+		return
+	}
+
+	message, err := session.ChannelMessage(channelID, ticket.MessageID)
+	if err != nil {
+		config.Logger.Warnln("Failed to get ticket opening message: ", err)
+		return
+	}
+
+	// The info embed is always the last one
+	embeds := message.Embeds
+	info := ticketInfoEmbed(conf.category(ticket.Category), ticket)
+	if len(embeds) > 0 {
+		embeds[len(embeds)-1] = info
+	} else {
+		embeds = []*discordgo.MessageEmbed{info}
+	}
+
+	_, err = session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:      ticket.MessageID,
+		Channel: channelID,
+		Embeds:  &embeds,
+	})
+	if err != nil {
+		config.Logger.Warnln("Failed to update ticket info: ", err)
+	}
+}
+
+// staffTicket returns the open ticket of the channel if the interaction comes from its staff,
+// otherwise responds with the reason and returns false.
+func (m *TicketCog) staffTicket(session *discordgo.Session, interaction *discordgo.Interaction) (*TicketGuildConfig, Ticket, bool) {
+
+	conf, ok := m.Config.Guilds[interaction.GuildID]
+	if !ok {
+		return nil, Ticket{}, false
+	}
+
+	ticket, ok := m.getTicket(interaction.ChannelID)
+	if !ok || ticket.Status != TicketOpen {
+		discord.SendEphemeralResponse(session, interaction, "This can only be used in an open ticket.")
+		return nil, Ticket{}, false
+	}
+
+	if !discord.MemberHasAnyRole(interaction.Member, conf.staffRoles(conf.category(ticket.Category))) {
+		sendNoPermission(session, interaction, conf)
+		return nil, Ticket{}, false
+	}
+	return conf, ticket, true
+}
+
+// respondTicketAudit posts the message to the ticket as the response of the interaction.
+func respondTicketAudit(session *discordgo.Session, interaction *discordgo.Interaction, message, userID string) {
+	values := discord.TemplateValuesFor(session, interaction.GuildID, interaction.ChannelID, nil).SetUserID(userID)
+	values["staff_id"] = interaction.Member.User.ID
+	err := session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:         discord.ApplyTemplate(message, values),
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+	if err != nil {
+		config.Logger.Errorln("Failed to respond to ticket interaction: ", err)
+	}
+}
+
+func (m *TicketCog) handleClaimTicket(session *discordgo.Session, interaction *discordgo.Interaction) {
+
+	conf, ticket, ok := m.staffTicket(session, interaction)
+	if !ok {
+		return
+	}
+
+	staffID := interaction.Member.User.ID
+	if ticket.ClaimedBy == staffID {
+		discord.SendEphemeralResponse(session, interaction, "You already claimed this ticket.")
+		return
+	}
+	if ticket.ClaimedBy != "" {
+		discord.SendEphemeralResponse(session, interaction, fmt.Sprintf("This ticket is already claimed by <@%s>, ask them to transfer it.", ticket.ClaimedBy))
+		return
+	}
+
+	m.updateTicket(ticket.ChannelID, func(ticket *Ticket) {
+		ticket.ClaimedBy = staffID
+		ticket.ClaimedAt = time.Now()
+		if ticket.FirstClaimAt.IsZero() {
+			ticket.FirstClaimAt = ticket.ClaimedAt
+		}
+	})
+	respondTicketAudit(session, interaction, conf.Messages.TicketClaimed, ticket.UserID)
+	m.refreshTicketInfo(session, ticket.ChannelID)
+}
+
+func (m *TicketCog) handleUnclaimTicket(session *discordgo.Session, interaction *discordgo.Interaction) {
+
+	conf, ticket, ok := m.staffTicket(session, interaction)
+	if !ok {
+		return
+	}
+
+	if ticket.ClaimedBy == "" {
+		discord.SendEphemeralResponse(session, interaction, "This ticket isnt claimed.")
+		return
+	}
+	if ticket.ClaimedBy != interaction.Member.User.ID {
+		discord.SendEphemeralResponse(session, interaction, fmt.Sprintf("This ticket is claimed by <@%s>.", ticket.ClaimedBy))
+		return
+	}
+
+	m.updateTicket(ticket.ChannelID, func(ticket *Ticket) {
+		ticket.ClaimedBy = ""
+		ticket.ClaimedAt = time.Time{}
+	})
+	respondTicketAudit(session, interaction, conf.Messages.TicketUnclaimed, ticket.UserID)
+	m.refreshTicketInfo(session, ticket.ChannelID)
+}
+
+func (m *TicketCog) handleTransferTicket(session *discordgo.Session, interaction *discordgo.Interaction, staff *discordgo.User) {
+
+	conf, ticket, ok := m.staffTicket(session, interaction)
+	if !ok {
+		return
+	}
+
+	// Unclaimed tickets can be assigned by any staff member, claimed ones only by the claimer
+	if ticket.ClaimedBy != "" && ticket.ClaimedBy != interaction.Member.User.ID {
+		discord.SendEphemeralResponse(session, interaction, fmt.Sprintf("This ticket is claimed by <@%s>.", ticket.ClaimedBy))
+		return
+	}
+	if staff.ID == ticket.ClaimedBy {
+		discord.SendEphemeralResponse(session, interaction, "That user already has this ticket.")
+		return
+	}
+
+	member, err := session.GuildMember(interaction.GuildID, staff.ID)
+	if err != nil || !discord.MemberHasAnyRole(member, conf.staffRoles(conf.category(ticket.Category))) {
+		discord.SendEphemeralResponse(session, interaction, "Tickets can only be transferred to staff.")
+		return
+	}
+
+	if err := addTicketMember(session, ticket, staff.ID); err != nil {
+		config.Logger.Warnln("Failed to add", staff.ID, "to ticket:", err)
+	}
+
+	m.updateTicket(ticket.ChannelID, func(ticket *Ticket) {
+		ticket.ClaimedBy = staff.ID
+		ticket.ClaimedAt = time.Now()
+		if ticket.FirstClaimAt.IsZero() {
+			ticket.FirstClaimAt = ticket.ClaimedAt
+		}
+	})
+	respondTicketAudit(session, interaction, conf.Messages.TicketTransferred, staff.ID)
+	m.refreshTicketInfo(session, ticket.ChannelID)
+}
+
+// handleTicketDashboard lists the open tickets of the guild to staff.
+func (m *TicketCog) handleTicketDashboard(session *discordgo.Session, interaction *discordgo.Interaction) {
+
+	conf, ok := m.Config.Guilds[interaction.GuildID]
+	if !ok {
+		return
+	}
+
+	if !conf.isStaff(interaction.Member) {
+		sendNoPermission(session, interaction, conf)
+		return
+	}
+
+	m.StateMutex.Lock()
+	tickets := []Ticket{}
+	for _, ticket := range m.State.Tickets {
+		if ticket.GuildID == interaction.GuildID && ticket.Status == TicketOpen {
+			tickets = append(tickets, *ticket)
+		}
+	}
+	m.StateMutex.Unlock()
+
+	sort.Slice(tickets, func(i, j int) bool {
+		return tickets[i].CreatedAt.Before(tickets[j].CreatedAt)
+	})
+
+	description := "There are no open tickets."
+	if len(tickets) > 0 {
+		lines := []string{}
+		length := 0
+		for i, ticket := range tickets {
+			claimedBy := "unclaimed"
+			if ticket.ClaimedBy != "" {
+				claimedBy = "<@" + ticket.ClaimedBy + ">"
+			}
+			lastActivity := ticket.LastActivity
+			if lastActivity.IsZero() {
+				lastActivity = ticket.CreatedAt
+			}
+
+			line := fmt.Sprintf("<#%s> by <@%s>, %s, opened <t:%d:R>, active <t:%d:R>",
+				ticket.ChannelID, ticket.UserID, claimedBy, ticket.CreatedAt.Unix(), lastActivity.Unix())
+
+			// Embed descriptions are limited to 4096 characters
+			if length+len(line) > 4000 {
+				lines = append(lines, fmt.Sprintf("...and %d more", len(tickets)-i))
+				break
+			}
+			lines = append(lines, line)
+			length += len(line) + 1
+		}
+		description = strings.Join(lines, "\n")
+	}
+
+	err := session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{{
+				Title:       fmt.Sprintf("Open tickets (%d)", len(tickets)),
+				Description: description,
+			}},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		config.Logger.Errorln("Failed to send ticket dashboard: ", err)
+	}
+}
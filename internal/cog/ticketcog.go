@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"phoenixbot/internal/config"
 	"phoenixbot/internal/discord"
+	"phoenixbot/internal/storage"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -16,6 +19,19 @@ type TicketGuildConfig struct {
 		NoPermission      string `json:"NoPermission"`
 		CloseTicketPrompt string `json:"CloseTicketPrompt"`
 		AlreadyHasTicket  string `json:"AlreadyHasTicket"`
+		TicketAccepted    string `json:"TicketAccepted"`
+		TicketDenied      string `json:"TicketDenied"`
+		OnCooldown        string `json:"OnCooldown"`
+		MemberAdded       string `json:"MemberAdded"`
+		MemberRemoved     string `json:"MemberRemoved"`
+		InactiveReminder  string `json:"InactiveReminder"`
+		InactiveWarning   string `json:"InactiveWarning"`
+		TicketClaimed     string `json:"TicketClaimed"`
+		TicketUnclaimed   string `json:"TicketUnclaimed"`
+		TicketTransferred string `json:"TicketTransferred"`
+		Blacklisted       string `json:"Blacklisted"`
+		AccountTooNew     string `json:"AccountTooNew"`
+		MemberTooNew      string `json:"MemberTooNew"`
 
 		TicketChannelMessage discord.MessageData `json:"TicketChannelMessage"`
 		TicketCreateMessage  discord.MessageData `json:"TicketCreateMessage"`
@@ -24,6 +40,112 @@ type TicketGuildConfig struct {
 	Channel  string            `json:"Channel"`
 	AddRoles map[string]string `json:"AddRoles"`
 	Enabled  bool              `json:"Enabled"`
+
+	Mode            string `json:"Mode"`            // "thread" (default) for private threads or "channel" for text channels
+	ChannelCategory string `json:"ChannelCategory"` // Category the ticket channels are created in
+
+	StaffRoles   map[string]string `json:"StaffRoles"`   // Roles allowed to accept and deny tickets (name and ID)
+	DenyCooldown int               `json:"DenyCooldown"` // Minutes before a denied user can open a new ticket
+
+	StatusPrefixes map[string]string `json:"StatusPrefixes"` // Maps an outcome to the prefix added to the thread name when the ticket is closed
+
+	CreateCooldown int `json:"CreateCooldown"` // Minutes between opening tickets per user
+	MinAccountAge  int `json:"MinAccountAge"`  // Days since the discord account was created
+	MinMemberAge   int `json:"MinMemberAge"`   // Days since the user joined the server
+
+	Categories []*TicketCategory `json:"Categories"` // Ticket types shown on the apply message
+
+	TranscriptChannel string `json:"TranscriptChannel"` // Staff channel where transcripts of closed tickets are posted
+	DMTranscript      bool   `json:"DMTranscript"`      // Also send the transcript to the ticket owner
+
+	CloseMode            string `json:"CloseMode"`            // "delete" (default) or "archive" to lock closed threads instead
+	ArchiveRetentionDays int    `json:"ArchiveRetentionDays"` // Days before archived tickets are deleted, 0 keeps them
+
+	StatsChannel string `json:"StatsChannel"` // Staff channel for the weekly ticket summary, empty disables
+}
+
+const (
+	TicketCloseDelete  = "delete"
+	TicketCloseArchive = "archive"
+)
+
+// TicketCategory is a type of ticket with its own button on the apply message.
+type TicketCategory struct {
+	Id             string              `json:"Id"`
+	Label          string              `json:"Label"`
+	Emoji          string              `json:"Emoji"`
+	WelcomeMessage discord.MessageData `json:"WelcomeMessage"`
+	StaffRoles     map[string]string   `json:"StaffRoles"` // Defaults to the guild StaffRoles
+	AddRoles       map[string]string   `json:"AddRoles"`   // Defaults to the guild AddRoles
+	ThreadName     string              `json:"ThreadName"` // Supports {category}, {number}, {username}, {user_id} and {user_short}
+	MaxOpen        int                 `json:"MaxOpen"`    // Open tickets of this type per user, defaults to 1
+	Review         bool                `json:"Review"`     // Show accept and deny buttons to staff
+	Questions      []TicketQuestion    `json:"Questions"`  // Asked in a modal before the ticket is created, at most 5
+
+	// Hours without messages before the owner is reminded, warned and the ticket is closed, 0 disables
+	ReminderHours  int `json:"ReminderHours"`
+	WarningHours   int `json:"WarningHours"`
+	AutoCloseHours int `json:"AutoCloseHours"`
+}
+
+type TicketQuestion struct {
+	Id          string `json:"Id"`
+	Label       string `json:"Label"` // At most 45 characters
+	Placeholder string `json:"Placeholder"`
+	Paragraph   bool   `json:"Paragraph"` // Multi line answer
+	Required    bool   `json:"Required"`
+	MinLength   int    `json:"MinLength"`
+	MaxLength   int    `json:"MaxLength"`
+}
+
+// category returns the ticket category by id, an empty id returns the first one.
+func (conf *TicketGuildConfig) category(id string) *TicketCategory {
+	for _, category := range conf.Categories {
+		if id == "" || category.Id == id {
+			return category
+		}
+	}
+	return nil
+}
+
+func (conf *TicketGuildConfig) staffRoles(category *TicketCategory) map[string]string {
+	if category != nil && len(category.StaffRoles) > 0 {
+		return category.StaffRoles
+	}
+	return conf.StaffRoles
+}
+
+// isStaff reports whether the member is staff of the guild or any of its ticket types.
+func (conf *TicketGuildConfig) isStaff(member *discordgo.Member) bool {
+	if discord.MemberHasAnyRole(member, conf.StaffRoles) {
+		return true
+	}
+	for _, category := range conf.Categories {
+		if discord.MemberHasAnyRole(member, category.StaffRoles) {
+			return true
+		}
+	}
+	return false
+}
+
+func (conf *TicketGuildConfig) addRoles(category *TicketCategory) map[string]string {
+	if category != nil && len(category.AddRoles) > 0 {
+		return category.AddRoles
+	}
+	return conf.AddRoles
+}
+
+const ticketStateFile = "ticket.json"
+
+// TicketState is persisted between restarts.
+type TicketState struct {
+	Panels      map[string]string    `json:"Panels"`      // Maps ticket channel id to apply message id
+	Tickets     map[string]*Ticket   `json:"Tickets"`     // Maps thread id to ticket
+	StatsReport map[string]time.Time `json:"StatsReport"` // Maps guild id to when the last weekly summary was posted
+
+	Blacklist map[string]map[string]*TicketBlacklistEntry `json:"Blacklist"` // Maps guild id and user id to the entry
+	Cooldowns map[string]map[string]time.Time             `json:"Cooldowns"` // Maps guild id and user id to when they can open a new ticket
+	Counters  map[string]int                              `json:"Counters"`  // Maps guild id to the number of the last ticket
 }
 
 type TicketConfig struct {
@@ -38,7 +160,8 @@ type TicketCog struct {
 	Session *discordgo.Session
 	Config  *TicketConfig
 
-	TicketUsers sync.Map // Maps user id to thread id
+	State      TicketState
+	StateMutex sync.Mutex
 }
 
 func (m *TicketCog) Name() string {
@@ -53,6 +176,35 @@ func (m *TicketCog) Init() error {
 	}
 	m.Config = &ticketConfig
 
+	if err := validateMessages(m.ConfigName, m.Config); err != nil {
+		return err
+	}
+	if err := m.validateButtonRows(); err != nil {
+		return err
+	}
+
+	if err := storage.Load(ticketStateFile, &m.State); err != nil {
+		config.Logger.Errorln(err)
+	}
+	if m.State.Panels == nil {
+		m.State.Panels = make(map[string]string)
+	}
+	if m.State.Tickets == nil {
+		m.State.Tickets = make(map[string]*Ticket)
+	}
+	if m.State.StatsReport == nil {
+		m.State.StatsReport = make(map[string]time.Time)
+	}
+	if m.State.Blacklist == nil {
+		m.State.Blacklist = make(map[string]map[string]*TicketBlacklistEntry)
+	}
+	if m.State.Cooldowns == nil {
+		m.State.Cooldowns = make(map[string]map[string]time.Time)
+	}
+	if m.State.Counters == nil {
+		m.State.Counters = make(map[string]int)
+	}
+
 	for guild, tic := range m.Config.Guilds {
 		if !config.IsGuildEnabled(guild) {
 			continue
@@ -62,16 +214,100 @@ func (m *TicketCog) Init() error {
 			continue
 		}
 
-		discord.ClearMessagesOnChannel(m.Session, tic.Channel, nil)
-		m.sendApplyMessage(guild, tic.Channel)
+		// Configs without categories keep the single apply button
+		if len(tic.Categories) == 0 {
+			tic.Categories = []*TicketCategory{{
+				Id:             "apply",
+				Label:          "Apply",
+				WelcomeMessage: tic.Messages.TicketChannelMessage,
+				ThreadName:     "ticket-{number}-{username}",
+				Review:         true,
+			}}
+		}
 	}
 
+	m.Session.AddHandlerOnce(func(s *discordgo.Session, r *discordgo.Ready) {
+		m.reconcileTickets(s)
+
+		for guild, tic := range m.Config.Guilds {
+			if !config.IsGuildEnabled(guild) || !tic.Enabled {
+				continue
+			}
+			m.sendApplyMessage(guild, tic.Channel)
+		}
+
+		go m.runScheduler(s)
+	})
+
 	m.Session.AddHandler(m.handleInteractionCreate)
+	m.Session.AddHandler(m.handleMessageCreate)
+	m.Session.AddHandler(m.handleThreadUpdate)
+	m.Session.AddHandler(m.handleThreadDelete)
+	m.Session.AddHandler(m.handleChannelDelete)
 
 	config.Logger.Infoln(m.Name(), "initialized!")
 	return nil
 }
 
+func (m *TicketCog) CommandsLoaded() bool {
+	return m.Config != nil
+}
+
+// Commands returns the ticket commands of guilds with tickets enabled.
+func (m *TicketCog) Commands(guildID string) []*discordgo.ApplicationCommand {
+	if m.Config == nil {
+		return nil
+	}
+	conf, ok := m.Config.Guilds[guildID]
+	if !ok || !conf.Enabled || !config.IsGuildEnabled(guildID) {
+		return nil
+	}
+	return []*discordgo.ApplicationCommand{ticketCommand(), ticketStatsCommand()}
+}
+
+// validateButtonRows checks that the configured link buttons leave room for the ticket buttons,
+// discord rejects the whole message when it has too many rows.
+func (m *TicketCog) validateButtonRows() error {
+	guilds := make([]string, 0, len(m.Config.Guilds))
+	for guild := range m.Config.Guilds {
+		guilds = append(guilds, guild)
+	}
+	sort.Strings(guilds)
+
+	errs := []error{}
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, guild := range guilds {
+		tic := m.Config.Guilds[guild]
+		path := "Guilds." + guild
+
+		// Configs without categories get a single apply button with the channel message as welcome
+		if len(tic.Categories) == 0 {
+			check(tic.Messages.TicketCreateMessage.ValidateReservedRows(path+".Messages.TicketCreateMessage", 1))
+			check(tic.Messages.TicketChannelMessage.ValidateReservedRows(path+".Messages.TicketChannelMessage", 1))
+			continue
+		}
+
+		categoryRows := discord.RowCount(len(tic.Categories))
+		check(tic.Messages.TicketCreateMessage.ValidateReservedRows(path+".Messages.TicketCreateMessage", categoryRows))
+		for i, category := range tic.Categories {
+			// The ticket actions take one row of the welcome message
+			check(category.WelcomeMessage.ValidateReservedRows(fmt.Sprintf("%s.Categories[%d].WelcomeMessage", path, i), 1))
+		}
+	}
+
+	for _, err := range errs {
+		config.Logger.Errorf("Invalid message in %s: %v", m.ConfigName, err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s has %d messages with too many buttons", m.ConfigName, len(errs))
+	}
+	return nil
+}
+
 func (m *TicketCog) sendApplyMessage(guildID string, channelID string) {
 
 	conf, ok := m.Config.Guilds[guildID]
@@ -79,57 +315,126 @@ func (m *TicketCog) sendApplyMessage(guildID string, channelID string) {
 		return
 	}
 
-	message, err := discord.CreateMessageSend(conf.Messages.TicketCreateMessage)
+	values := discord.TemplateValuesFor(m.Session, guildID, channelID, nil)
+	message, err := discord.CreateMessageSend(conf.Messages.TicketCreateMessage, values)
 	if err != nil {
-		config.Logger.Errorln(err)
+		config.Logger.Errorln("Failed to create apply message: ", err)
+		return
 	}
 
-	applyButton := discordgo.Button{
-		Label:    "Apply",
-		Style:    discordgo.PrimaryButton,
-		CustomID: "create_ticket_button",
+	buttons := []discordgo.MessageComponent{}
+	for _, category := range conf.Categories {
+		button := discordgo.Button{
+			Label:    category.Label,
+			Style:    discordgo.PrimaryButton,
+			CustomID: "create_ticket_button:" + category.Id,
+		}
+		if category.Emoji != "" {
+			button.Emoji = &discordgo.ComponentEmoji{Name: category.Emoji}
+		}
+
+		buttons = append(buttons, button)
 	}
 
-	message.Components = []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{applyButton}}}
+	// Ticket buttons go first, configured link buttons below them
+	message.Components = append(discord.ButtonRows(buttons), message.Components...)
 
-	_, err = m.Session.ChannelMessageSendComplex(channelID, message)
+	m.StateMutex.Lock()
+	messageID := m.State.Panels[channelID]
+	m.StateMutex.Unlock()
+
+	// Reuse the apply message from the previous run and remove only our own leftovers
+	if discord.ClearStaleOwnMessages(m.Session, channelID, messageID) {
+		_, err = m.Session.ChannelMessageEditComplex(discord.MessageEditFromSend(channelID, messageID, message))
+		if err == nil {
+			return
+		}
+		config.Logger.Warnln("Failed to edit apply message, sending a new one:", err)
+	}
+
+	msg, err := m.Session.ChannelMessageSendComplex(channelID, message)
 	if err != nil {
 		config.Logger.Errorln("Error sending apply message:", err)
+		return
+	}
+
+	m.StateMutex.Lock()
+	m.State.Panels[channelID] = msg.ID
+	m.saveState()
+	m.StateMutex.Unlock()
+}
+
+// saveState persists the ticket state, StateMutex must be held by the caller.
+func (m *TicketCog) saveState() {
+	if err := storage.Save(ticketStateFile, &m.State); err != nil {
+		config.Logger.Errorln("Failed to save ticket state:", err)
 	}
 }
 
 func (m *TicketCog) handleInteractionCreate(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
 
+	if interaction.Type == discordgo.InteractionApplicationCommand {
+		switch interaction.ApplicationCommandData().Name {
+		case "ticket":
+			m.handleTicketCommand(session, interaction.Interaction)
+		case "ticketstats":
+			m.handleTicketStatsCommand(session, interaction.Interaction)
+		}
+		return
+	}
+
+	if interaction.Type == discordgo.InteractionModalSubmit {
+		customID, argument, _ := strings.Cut(interaction.ModalSubmitData().CustomID, ":")
+		if customID == "ticket_modal" {
+			m.handleCreateTicket(session, interaction.Interaction, argument)
+		}
+		return
+	}
+
 	if interaction.Type != discordgo.InteractionMessageComponent {
 		return
 	}
 
-	customID := interaction.MessageComponentData().CustomID
+	customID, argument, _ := strings.Cut(interaction.MessageComponentData().CustomID, ":")
 
 	switch customID {
 	case "create_ticket_button":
-		m.handleCreateTicket(session, interaction.Interaction)
+		m.handleCreateTicket(session, interaction.Interaction, argument)
 	case "close_ticket_button":
 		m.handleCloseTicketPrompt(session, interaction.Interaction)
 	case "confirm_close_ticket_button":
 		m.handleConfirmCloseTicket(session, interaction.Interaction)
 	case "cancel_close_ticket_button":
 		m.handleCancelCloseTicket(session, interaction.Interaction)
+	case "accept_ticket_button":
+		m.handleReviewTicket(session, interaction.Interaction, true)
+	case "deny_ticket_button":
+		m.handleReviewTicket(session, interaction.Interaction, false)
+	case "claim_ticket_button":
+		m.handleClaimTicket(session, interaction.Interaction)
+	case "reopen_ticket_button":
+		m.handleReopenTicket(session, interaction.Interaction, interaction.ChannelID)
 	}
 }
 
-func (m *TicketCog) handleCreateTicket(session *discordgo.Session, interaction *discordgo.Interaction) {
+func (m *TicketCog) handleCreateTicket(session *discordgo.Session, interaction *discordgo.Interaction, categoryID string) {
 
 	conf, ok := m.Config.Guilds[interaction.GuildID]
 	if !ok {
 		return
 	}
 
-	var userId string
+	category := conf.category(categoryID)
+	if category == nil {
+		discord.SendEphemeralResponse(session, interaction, "This ticket type no longer exists.")
+		return
+	}
+
+	var user *discordgo.User
 	if interaction.User != nil {
-		userId = interaction.User.ID
+		user = interaction.User
 	} else if interaction.Member != nil && interaction.Member.User != nil {
-		userId = interaction.Member.User.ID
+		user = interaction.Member.User
 	} else {
 		config.Logger.Errorln("Failed to retrieve user information from interaction")
 		session.InteractionRespond(interaction, &discordgo.InteractionResponse{
@@ -142,59 +447,249 @@ func (m *TicketCog) handleCreateTicket(session *discordgo.Session, interaction *
 		return
 	}
 
+	userId := user.ID
+	values := discord.TemplateValuesFor(session, interaction.GuildID, interaction.ChannelID, user)
+
+	maxOpen := category.MaxOpen
+	if maxOpen <= 0 {
+		maxOpen = 1
+	}
+
 	// Check if user already has tikcet
-	if _, alrd := m.TicketUsers.Load(userId); alrd {
+	if m.countOpenTickets(interaction.GuildID, userId, category.Id) >= maxOpen {
 		session.InteractionRespond(interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: conf.Messages.AlreadyHasTicket,
+				Content: discord.ApplyTemplate(conf.Messages.AlreadyHasTicket, values),
 				Flags:   discordgo.MessageFlagsEphemeral,
 			},
 		})
 		return
 	}
 
-	// Create ticket thread
-	channeldId := interaction.ChannelID
-	threadName := fmt.Sprintf("ticket-%s", userId[:6])
-	thread, err := session.ThreadStart(channeldId, threadName, discordgo.ChannelTypeGuildPrivateThread, 4320)
-	if err != nil {
-		config.Logger.Errorln("Failed to create thread: ", err)
+	if denied, denial := m.ticketDenial(conf, interaction.GuildID, user, interaction.Member, values); denied {
+		discord.SendEphemeralResponse(session, interaction, denial)
+		return
+	}
+
+	// Questions are answered in a modal, the ticket is created once it is submitted
+	if len(category.Questions) > 0 && interaction.Type == discordgo.InteractionMessageComponent {
+		if err := m.sendTicketModal(session, interaction, category); err != nil {
+			config.Logger.Errorln("Failed to send ticket modal: ", err)
+		}
 		return
 	}
 
-	m.TicketUsers.Store(userId, thread.ID)
-	responseMessage := strings.NewReplacer("{channel}", thread.Mention()).Replace(conf.Messages.TicketCreated)
+	var answers []TicketAnswer
+	if interaction.Type == discordgo.InteractionModalSubmit {
+		answers = ticketAnswers(category, interaction.ModalSubmitData())
+	}
+
+	// Create ticket thread, or a channel in channel mode
+	channeldId := interaction.ChannelID
+	number := m.nextTicketNumber(interaction.GuildID)
+	threadName := ticketThreadName(category, user, number)
+
+	var thread *discordgo.Channel
+	var err error
+	if conf.Mode == TicketModeChannel {
+		thread, err = m.createTicketChannel(session, conf, category, interaction.GuildID, userId, threadName)
+		if err != nil {
+			config.Logger.Errorln("Failed to create ticket channel: ", err)
+			return
+		}
+		channeldId = thread.ParentID
+	} else {
+		autoArchive := 4320
+		if category.AutoCloseHours > 0 {
+			// Keep discord from archiving the thread before the inactivity timers close it
+			autoArchive = 10080
+		}
+		thread, err = session.ThreadStart(channeldId, threadName, discordgo.ChannelTypeGuildPrivateThread, autoArchive)
+		if err != nil {
+			config.Logger.Errorln("Failed to create thread: ", err)
+			return
+		}
+	}
+
+	ticket := Ticket{
+		UserID:    userId,
+		ChannelID: thread.ID,
+		ParentID:  channeldId,
+		GuildID:   interaction.GuildID,
+		Category:  category.Id,
+		Status:    TicketOpen,
+		Number:    number,
+		Name:      threadName,
+		Answers:   answers,
+	}
+	if conf.Mode == TicketModeChannel {
+		ticket.Mode = TicketModeChannel
+	}
+	m.addTicket(ticket)
+	if conf.CreateCooldown > 0 {
+		m.setCooldown(interaction.GuildID, userId, time.Now().Add(time.Duration(conf.CreateCooldown)*time.Minute))
+	}
+	if ticket.Mode != TicketModeChannel {
+		// Staff roles are part of the permissions of ticket channels
+		go m.addStaffToThread(session, interaction.GuildID, thread.ID, conf.staffRoles(category))
+	}
+
+	values = discord.TemplateValuesFor(session, interaction.GuildID, thread.ID, user)
 	session.InteractionRespond(interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content: responseMessage,
+			Content: discord.ApplyTemplate(conf.Messages.TicketCreated, values),
 			Flags:   discordgo.MessageFlagsEphemeral,
 		},
 	})
 
-	messend, err := discord.CreateMessageSend(conf.Messages.TicketChannelMessage)
+	messend, err := discord.CreateMessageSend(category.WelcomeMessage, values)
 	if err != nil {
-		config.Logger.Errorln("error creating MessageSend of TickedChannelMessage: ", err)
+		// The ticket exists already, it still needs its buttons and info embed
+		config.Logger.Errorln("error creating MessageSend of WelcomeMessage: ", err)
+		messend = &discordgo.MessageSend{}
 	}
 
-	replacer := strings.NewReplacer("{user_id}", userId)
-	messend.Content = replacer.Replace(messend.Content)
-
 	closeTicketButton := discordgo.Button{
 		Label:    "Close ticket",
 		Style:    discordgo.DangerButton,
 		CustomID: "close_ticket_button",
 	}
 
-	messend.Components = []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{closeTicketButton}}}
+	acceptTicketButton := discordgo.Button{
+		Label:    "Accept",
+		Style:    discordgo.SuccessButton,
+		CustomID: "accept_ticket_button",
+	}
+
+	denyTicketButton := discordgo.Button{
+		Label:    "Deny",
+		Style:    discordgo.SecondaryButton,
+		CustomID: "deny_ticket_button",
+	}
 
-	_, err = session.ChannelMessageSendComplex(thread.ID, messend)
+	claimTicketButton := discordgo.Button{
+		Label:    "Claim",
+		Style:    discordgo.PrimaryButton,
+		CustomID: "claim_ticket_button",
+	}
+
+	buttons := []discordgo.MessageComponent{claimTicketButton, closeTicketButton}
+	if category.Review {
+		buttons = []discordgo.MessageComponent{acceptTicketButton, denyTicketButton, claimTicketButton, closeTicketButton}
+	}
+
+	messend.Components = append(discord.ButtonRows(buttons), messend.Components...)
+
+	// The info embed goes last so it can be updated without touching the welcome embeds
+	messend.Embeds = append(messend.Embeds, ticketInfoEmbed(category, ticket))
+
+	welcome, err := session.ChannelMessageSendComplex(thread.ID, messend)
 	if err != nil {
 		config.Logger.Errorln(err)
+	} else {
+		m.updateTicket(thread.ID, func(ticket *Ticket) {
+			ticket.MessageID = welcome.ID
+		})
+	}
+
+	if len(answers) > 0 {
+		_, err = session.ChannelMessageSendEmbed(thread.ID, ticketAnswersEmbed(category, user, answers))
+		if err != nil {
+			config.Logger.Errorln("Failed to send ticket answers: ", err)
+		}
 	}
 }
 
+func (m *TicketCog) sendTicketModal(session *discordgo.Session, interaction *discordgo.Interaction, category *TicketCategory) error {
+
+	rows := []discordgo.MessageComponent{}
+	for _, question := range category.Questions {
+		style := discordgo.TextInputShort
+		if question.Paragraph {
+			style = discordgo.TextInputParagraph
+		}
+
+		rows = append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.TextInput{
+				CustomID:    question.Id,
+				Label:       question.Label,
+				Style:       style,
+				Placeholder: question.Placeholder,
+				Required:    question.Required,
+				MinLength:   question.MinLength,
+				MaxLength:   question.MaxLength,
+			},
+		}})
+
+		// Discord allows 5 inputs per modal
+		if len(rows) == 5 {
+			break
+		}
+	}
+
+	return session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID:   "ticket_modal:" + category.Id,
+			Title:      category.Label,
+			Components: rows,
+		},
+	})
+}
+
+// ticketAnswers reads the submitted modal answers in the order of the category questions.
+func ticketAnswers(category *TicketCategory, data discordgo.ModalSubmitInteractionData) []TicketAnswer {
+	values := make(map[string]string)
+	for _, row := range data.Components {
+		actionsRow, ok := row.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, component := range actionsRow.Components {
+			if input, ok := component.(*discordgo.TextInput); ok {
+				values[input.CustomID] = input.Value
+			}
+		}
+	}
+
+	answers := []TicketAnswer{}
+	for _, question := range category.Questions {
+		value, ok := values[question.Id]
+		if !ok {
+			continue
+		}
+		answers = append(answers, TicketAnswer{Question: question.Label, Answer: value})
+	}
+	return answers
+}
+
+func ticketAnswersEmbed(category *TicketCategory, user *discordgo.User, answers []TicketAnswer) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title: category.Label,
+		Author: &discordgo.MessageEmbedAuthor{
+			Name:    user.Username,
+			IconURL: user.AvatarURL(""),
+		},
+		Color:     0x00AAFF,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	for _, answer := range answers {
+		value := answer.Answer
+		if strings.TrimSpace(value) == "" {
+			value = "-"
+		}
+		// Embed field values are limited to 1024 characters, the full answer stays in the ticket record
+		if r := []rune(value); len(r) > 1024 {
+			value = string(r[:1021]) + "..."
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: answer.Question, Value: value})
+	}
+	return embed
+}
+
 func (m *TicketCog) handleCloseTicketPrompt(session *discordgo.Session, interaction *discordgo.Interaction) {
 
 	conf, ok := m.Config.Guilds[interaction.GuildID]
@@ -202,7 +697,14 @@ func (m *TicketCog) handleCloseTicketPrompt(session *discordgo.Session, interact
 		return
 	}
 
-	closePrompt := conf.Messages.CloseTicketPrompt
+	ticket, _ := m.getTicket(interaction.ChannelID)
+	if !m.canCloseTicket(conf, ticket, interaction.Member) {
+		sendNoPermission(session, interaction, conf)
+		return
+	}
+
+	values := discord.TemplateValuesFor(session, interaction.GuildID, interaction.ChannelID, interaction.Member.User)
+	closePrompt := discord.ApplyTemplate(conf.Messages.CloseTicketPrompt, values)
 	session.InteractionRespond(interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
@@ -228,31 +730,159 @@ func (m *TicketCog) handleCloseTicketPrompt(session *discordgo.Session, interact
 }
 
 func (m *TicketCog) handleConfirmCloseTicket(session *discordgo.Session, interaction *discordgo.Interaction) {
-	userID := interaction.Member.User.ID
 	threadID := interaction.ChannelID
 
-	if _, err := session.ChannelDelete(threadID); err != nil {
+	conf, ok := m.Config.Guilds[interaction.GuildID]
+	if !ok {
+		return
+	}
+
+	ticket, ok := m.getTicket(threadID)
+	if !ok {
+		// Threads from before tickets were stored
+		ticket = Ticket{ChannelID: threadID, GuildID: interaction.GuildID}
+	}
+
+	if !m.canCloseTicket(conf, ticket, interaction.Member) {
+		sendNoPermission(session, interaction, conf)
+		return
+	}
+
+	// Transcript can take longer than the interaction response window
+	session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    "Closing ticket...",
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+
+	if err := m.closeTicket(session, ticket, interaction.Member.User.ID, OutcomeResolved); err != nil {
 		config.Logger.Errorln("Failed to delete thread: ", err)
-		session.InteractionRespond(interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Failed to delete the ticket.",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
+		session.FollowupMessageCreate(interaction, false, &discordgo.WebhookParams{
+			Content: "Failed to delete the ticket.",
+			Flags:   discordgo.MessageFlagsEphemeral,
 		})
+	}
+}
+
+// sendNoPermission responds with the no permission message of the guild.
+func sendNoPermission(session *discordgo.Session, interaction *discordgo.Interaction, conf *TicketGuildConfig) {
+	user := interaction.User
+	if interaction.Member != nil {
+		user = interaction.Member.User
+	}
+	values := discord.TemplateValuesFor(session, interaction.GuildID, interaction.ChannelID, user)
+	discord.SendEphemeralResponse(session, interaction, discord.ApplyTemplate(conf.Messages.NoPermission, values))
+}
+
+// canCloseTicket reports whether the member owns the ticket or is staff of its category.
+func (m *TicketCog) canCloseTicket(conf *TicketGuildConfig, ticket Ticket, member *discordgo.Member) bool {
+	if member == nil || member.User == nil {
+		return false
+	}
+	if ticket.UserID != "" && member.User.ID == ticket.UserID {
+		return true
+	}
+	return discord.MemberHasAnyRole(member, conf.staffRoles(conf.category(ticket.Category)))
+}
+
+// closeTicket saves a transcript of the ticket and deletes its thread, or archives it in archive mode.
+// The outcome is only recorded if the ticket doesnt have one yet.
+func (m *TicketCog) closeTicket(session *discordgo.Session, ticket Ticket, closedBy string, outcome string) error {
+	firstAttempt := true
+	m.updateTicket(ticket.ChannelID, func(ticket *Ticket) {
+		if ticket.Outcome == "" {
+			ticket.Outcome = outcome
+		}
+		firstAttempt = !ticket.TranscriptSent
+		ticket.TranscriptSent = true
+	})
+
+	// Retries of a failed close already sent the transcript
+	if firstAttempt {
+		m.sendTranscript(session, ticket, closedBy)
+	}
+
+	conf, ok := m.Config.Guilds[ticket.GuildID]
+	if ok && conf.CloseMode == TicketCloseArchive {
+		return m.archiveTicket(session, conf, ticket, closedBy)
+	}
+
+	if _, err := session.ChannelDelete(ticket.ChannelID); err != nil {
+		return err
+	}
+
+	m.setTicketStatus(ticket.ChannelID, TicketClosed)
+	return nil
+}
+
+func (m *TicketCog) archiveTicket(session *discordgo.Session, conf *TicketGuildConfig, ticket Ticket, closedBy string) error {
+	// Status first so the thread update event doesnt mark the ticket closed
+	m.setTicketStatus(ticket.ChannelID, TicketArchived)
+
+	// Archived threads cant receive messages, so post before archiving
+	_, err := session.ChannelMessageSendComplex(ticket.ChannelID, &discordgo.MessageSend{
+		Content: fmt.Sprintf("Ticket closed by <@%s>.", closedBy),
+		Components: []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "Reopen",
+				Style:    discordgo.SecondaryButton,
+				CustomID: "reopen_ticket_button",
+			},
+		}}},
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		config.Logger.Warnln("Failed to send ticket closed message: ", err)
+	}
+
+	// Reload for the outcome recorded by closeTicket
+	ticket, _ = m.getTicket(ticket.ChannelID)
+	return setTicketLocked(session, ticket, true, conf.statusThreadName(ticket, ticket.Outcome))
+}
+
+func (m *TicketCog) handleReopenTicket(session *discordgo.Session, interaction *discordgo.Interaction, threadID string) {
+
+	conf, ok := m.Config.Guilds[interaction.GuildID]
+	if !ok {
 		return
 	}
 
-	if _, exists := m.TicketUsers.Load(userID); exists {
-		m.TicketUsers.Delete(userID)
+	ticket, ok := m.getTicket(threadID)
+	if !ok {
+		discord.SendEphemeralResponse(session, interaction, "Couldnt find that ticket.")
+		return
 	}
-	session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+
+	if !discord.MemberHasAnyRole(interaction.Member, conf.staffRoles(conf.category(ticket.Category))) {
+		sendNoPermission(session, interaction, conf)
+		return
+	}
+
+	if ticket.Status != TicketArchived {
+		discord.SendEphemeralResponse(session, interaction, "Only archived tickets can be reopened.")
+		return
+	}
+
+	// Drop the status prefix again
+	if err := setTicketLocked(session, ticket, false, ticket.Name); err != nil {
+		config.Logger.Errorln("Failed to unlock ticket: ", err)
+		discord.SendEphemeralResponse(session, interaction, "Failed to reopen the ticket.")
+		return
+	}
+	m.setTicketStatus(threadID, TicketOpen)
+
+	err := session.InteractionRespond(interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content: "Ticket successfully closed.",
-			Flags:   discordgo.MessageFlagsEphemeral,
+			Content:         fmt.Sprintf("<#%s> reopened by <@%s>.", threadID, interaction.Member.User.ID),
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
 		},
 	})
+	if err != nil {
+		config.Logger.Errorln("Failed to respond to ticket reopen: ", err)
+	}
 }
 
 func (m *TicketCog) handleCancelCloseTicket(session *discordgo.Session, interaction *discordgo.Interaction) {
@@ -264,3 +894,118 @@ func (m *TicketCog) handleCancelCloseTicket(session *discordgo.Session, interact
 		},
 	})
 }
+
+func (m *TicketCog) handleReviewTicket(session *discordgo.Session, interaction *discordgo.Interaction, accepted bool) {
+
+	conf, ok := m.Config.Guilds[interaction.GuildID]
+	if !ok {
+		return
+	}
+
+	threadID := interaction.ChannelID
+	ticket, ok := m.getTicket(threadID)
+	if !ok || ticket.Status != TicketOpen {
+		discord.SendEphemeralResponse(session, interaction, "Couldnt find the owner of this ticket.")
+		return
+	}
+	ownerID := ticket.UserID
+	category := conf.category(ticket.Category)
+
+	if !discord.MemberHasAnyRole(interaction.Member, conf.staffRoles(category)) {
+		sendNoPermission(session, interaction, conf)
+		return
+	}
+
+	outcome := OutcomeDenied
+	reviewMessage := conf.Messages.TicketDenied
+	if accepted {
+		outcome = OutcomeAccepted
+		for name, roleID := range conf.addRoles(category) {
+			if err := session.GuildMemberRoleAdd(interaction.GuildID, ownerID, roleID); err != nil {
+				config.Logger.Errorf("Failed to add role %s to %s: %v", name, ownerID, err)
+			}
+		}
+		reviewMessage = conf.Messages.TicketAccepted
+	} else if conf.DenyCooldown > 0 {
+		m.setCooldown(interaction.GuildID, ownerID, time.Now().Add(time.Duration(conf.DenyCooldown)*time.Minute))
+	}
+
+	values := discord.TemplateValuesFor(session, interaction.GuildID, threadID, nil).SetUserID(ownerID)
+	values["staff_id"] = interaction.Member.User.ID
+	content := discord.ApplyTemplate(reviewMessage, values)
+
+	var err error
+	if conf.CloseMode == TicketCloseArchive {
+		err = session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+			},
+		})
+	} else {
+		// The thread is deleted right away, so the owner gets the outcome as a DM
+		if dmErr := sendDirectMessage(session, ownerID, content); dmErr != nil {
+			config.Logger.Warnln("Failed to dm ticket review: ", dmErr)
+		}
+		err = discord.SendEphemeralResponse(session, interaction, content)
+	}
+	if err != nil {
+		config.Logger.Errorln("Failed to respond to ticket review: ", err)
+	}
+
+	m.updateTicket(threadID, func(ticket *Ticket) {
+		ticket.Outcome = outcome
+		if ticket.FirstResponseAt.IsZero() {
+			ticket.FirstResponseAt = time.Now()
+		}
+	})
+
+	if err := m.closeTicket(session, ticket, interaction.Member.User.ID, outcome); err != nil {
+		config.Logger.Errorln("Failed to close reviewed ticket: ", err)
+	}
+}
+
+// sendDirectMessage sends the content to the user in a DM.
+func sendDirectMessage(session *discordgo.Session, userID, content string) error {
+	dm, err := session.UserChannelCreate(userID)
+	if err != nil {
+		return err
+	}
+	_, err = session.ChannelMessageSend(dm.ID, content)
+	return err
+}
+
+// ticketThreadName fills the thread name template of the category for the user.
+func ticketThreadName(category *TicketCategory, user *discordgo.User, number int) string {
+	template := category.ThreadName
+	if template == "" {
+		template = "{category}-{number}-{username}"
+	}
+
+	name := discord.ApplyTemplate(template, discord.TemplateValues{
+		"category":   category.Id,
+		"number":     fmt.Sprintf("%04d", number),
+		"username":   user.Username,
+		"user_id":    user.ID,
+		"user_short": user.ID[:6],
+	})
+	return truncateChannelName(name)
+}
+
+// statusThreadName returns the thread name of the ticket prefixed for the outcome,
+// or an empty string to keep the current name.
+func (conf *TicketGuildConfig) statusThreadName(ticket Ticket, outcome string) string {
+	prefix, ok := conf.StatusPrefixes[outcome]
+	if !ok || ticket.Name == "" {
+		return ""
+	}
+	return truncateChannelName(prefix + " " + ticket.Name)
+}
+
+// truncateChannelName cuts the name to the 100 characters discord allows for channel names.
+func truncateChannelName(name string) string {
+	if r := []rune(name); len(r) > 100 {
+		name = string(r[:100])
+	}
+	return name
+}
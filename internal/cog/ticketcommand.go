@@ -0,0 +1,169 @@
+package cog
+
+import (
+	"phoenixbot/internal/discord"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ticketCommand is the /ticket command with its staff subcommands.
+func ticketCommand() *discordgo.ApplicationCommand {
+	ticketChannelTypes := []discordgo.ChannelType{
+		discordgo.ChannelTypeGuildPrivateThread,
+		discordgo.ChannelTypeGuildPublicThread,
+		discordgo.ChannelTypeGuildText,
+	}
+
+	return &discordgo.ApplicationCommand{
+		Name:        "ticket",
+		Description: "Manage tickets",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "reopen",
+				Description: "Reopen an archived ticket",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "ticket",
+						Description:  "Ticket to reopen, defaults to this channel",
+						ChannelTypes: ticketChannelTypes,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "Add someone to this ticket",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "User to add",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Remove someone from this ticket",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "User to remove",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "unclaim",
+				Description: "Release your claim on this ticket",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "transfer",
+				Description: "Hand this ticket over to another staff member",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "staff",
+						Description: "Staff member taking over the ticket",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "dashboard",
+				Description: "List open tickets",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+				Name:        "blacklist",
+				Description: "Manage who can open tickets",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "add",
+						Description: "Stop someone from opening tickets",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionUser,
+								Name:        "user",
+								Description: "User to blacklist",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "reason",
+								Description: "Shown to the user when they try to open a ticket",
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "remove",
+						Description: "Allow someone to open tickets again",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionUser,
+								Name:        "user",
+								Description: "User to remove from the blacklist",
+								Required:    true,
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "list",
+						Description: "List blacklisted users",
+					},
+				},
+			},
+		},
+	}
+}
+
+func (m *TicketCog) handleTicketCommand(session *discordgo.Session, interaction *discordgo.Interaction) {
+
+	if _, ok := m.Config.Guilds[interaction.GuildID]; !ok {
+		return
+	}
+
+	data := interaction.ApplicationCommandData()
+	if len(data.Options) == 0 {
+		return
+	}
+	subcommand := data.Options[0]
+
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, option := range subcommand.Options {
+		options[option.Name] = option
+	}
+
+	switch subcommand.Name {
+	case "reopen":
+		threadID := interaction.ChannelID
+		if option, ok := options["ticket"]; ok {
+			threadID = option.Value.(string)
+		}
+		m.handleReopenTicket(session, interaction, threadID)
+	case "add":
+		m.handleTicketMember(session, interaction, options["user"].UserValue(nil), true)
+	case "remove":
+		m.handleTicketMember(session, interaction, options["user"].UserValue(nil), false)
+	case "unclaim":
+		m.handleUnclaimTicket(session, interaction)
+	case "transfer":
+		m.handleTransferTicket(session, interaction, options["staff"].UserValue(nil))
+	case "dashboard":
+		m.handleTicketDashboard(session, interaction)
+	case "blacklist":
+		m.handleBlacklistCommand(session, interaction, subcommand)
+	default:
+		discord.SendEphemeralResponse(session, interaction, "Unknown ticket command.")
+	}
+}
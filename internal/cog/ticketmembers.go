@@ -0,0 +1,57 @@
+package cog
+
+import (
+	"phoenixbot/internal/config"
+	"phoenixbot/internal/discord"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// addStaffToThread adds every member with one of the staff roles to a new ticket thread,
+// private threads are otherwise only visible to the applicant.
+func (m *TicketCog) addStaffToThread(session *discordgo.Session, guildID, threadID string, staffRoles map[string]string) {
+	members, err := discord.GuildMembersWithAnyRole(session, guildID, staffRoles)
+	if err != nil {
+		config.Logger.Errorln("Failed to list staff members: ", err)
+	}
+
+	for _, member := range members {
+		if err := session.ThreadMemberAdd(threadID, member.User.ID); err != nil {
+			config.Logger.Warnln("Failed to add", member.User.ID, "to ticket thread:", err)
+		}
+	}
+}
+
+// handleTicketMember adds or removes a user from the ticket the command was used in.
+func (m *TicketCog) handleTicketMember(session *discordgo.Session, interaction *discordgo.Interaction, user *discordgo.User, add bool) {
+
+	conf, ticket, ok := m.staffTicket(session, interaction)
+	if !ok {
+		return
+	}
+
+	message := conf.Messages.MemberAdded
+	if add {
+		err := addTicketMember(session, ticket, user.ID)
+		if err != nil {
+			config.Logger.Errorln("Failed to add member to ticket: ", err)
+			discord.SendEphemeralResponse(session, interaction, "Failed to add that user to the ticket.")
+			return
+		}
+	} else {
+		if user.ID == ticket.UserID {
+			discord.SendEphemeralResponse(session, interaction, "The ticket owner cant be removed from their ticket.")
+			return
+		}
+		err := removeTicketMember(session, ticket, user.ID)
+		if err != nil {
+			config.Logger.Errorln("Failed to remove member from ticket: ", err)
+			discord.SendEphemeralResponse(session, interaction, "Failed to remove that user from the ticket.")
+			return
+		}
+		message = conf.Messages.MemberRemoved
+	}
+
+	// The response is the audit message in the thread
+	respondTicketAudit(session, interaction, message, user.ID)
+}
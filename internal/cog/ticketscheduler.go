@@ -0,0 +1,155 @@
+package cog
+
+import (
+	"fmt"
+	"phoenixbot/internal/config"
+	"phoenixbot/internal/discord"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const ticketSchedulerInterval = time.Minute
+
+// ticketCloseRetry is how long a failed auto-close waits before it is tried again.
+const ticketCloseRetry = time.Hour
+
+// runScheduler periodically runs the time based ticket tasks. Timers are derived from
+// the stored ticket timestamps so they continue where they left off after a restart.
+func (m *TicketCog) runScheduler(session *discordgo.Session) {
+	ticker := time.NewTicker(ticketSchedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		m.checkInactiveTickets(session)
+		m.deleteExpiredTickets(session)
+		m.postWeeklyStats(session)
+		<-ticker.C
+	}
+}
+
+// handleMessageCreate records activity in open tickets and resets their inactivity timers.
+func (m *TicketCog) handleMessageCreate(session *discordgo.Session, message *discordgo.MessageCreate) {
+	if message.Author == nil || message.Author.Bot {
+		return
+	}
+
+	ticket, ok := m.getTicket(message.ChannelID)
+	if !ok || ticket.Status != TicketOpen {
+		return
+	}
+
+	if ticket.FirstResponseAt.IsZero() && message.Author.ID != ticket.UserID {
+		conf, ok := m.Config.Guilds[ticket.GuildID]
+		if ok && discord.MemberHasAnyRole(message.Member, conf.staffRoles(conf.category(ticket.Category))) {
+			m.updateTicket(message.ChannelID, func(ticket *Ticket) {
+				ticket.FirstResponseAt = time.Now()
+			})
+		}
+	}
+
+	// Avoid saving the state on every message of an active conversation
+	if !ticket.Reminded && !ticket.Warned && time.Since(ticket.LastActivity) < ticketSchedulerInterval {
+		return
+	}
+
+	m.updateTicket(message.ChannelID, func(ticket *Ticket) {
+		ticket.LastActivity = time.Now()
+		ticket.Reminded = false
+		ticket.Warned = false
+	})
+}
+
+// checkInactiveTickets reminds, warns and finally closes open tickets without recent activity.
+func (m *TicketCog) checkInactiveTickets(session *discordgo.Session) {
+	m.StateMutex.Lock()
+	tickets := []Ticket{}
+	for _, ticket := range m.State.Tickets {
+		if ticket.Status == TicketOpen && config.IsGuildEnabled(ticket.GuildID) {
+			tickets = append(tickets, *ticket)
+		}
+	}
+	m.StateMutex.Unlock()
+
+	for _, ticket := range tickets {
+		conf, ok := m.Config.Guilds[ticket.GuildID]
+		if !ok {
+			continue
+		}
+		category := conf.category(ticket.Category)
+		if category == nil {
+			continue
+		}
+
+		lastActivity := ticket.LastActivity
+		if lastActivity.IsZero() {
+			lastActivity = ticket.CreatedAt
+		}
+		inactive := time.Since(lastActivity)
+		closeAt := lastActivity.Add(time.Duration(category.AutoCloseHours) * time.Hour)
+
+		switch {
+		case category.AutoCloseHours > 0 && inactive >= time.Duration(category.AutoCloseHours)*time.Hour:
+			if time.Since(ticket.CloseFailedAt) < ticketCloseRetry {
+				continue
+			}
+			config.Logger.Infoln("Closing inactive ticket", ticket.ChannelID)
+			if err := m.closeTicket(session, ticket, session.State.User.ID, OutcomeAbandoned); err != nil {
+				config.Logger.Errorln("Failed to close inactive ticket, retrying in ", ticketCloseRetry, ": ", err)
+				m.updateTicket(ticket.ChannelID, func(ticket *Ticket) {
+					ticket.CloseFailedAt = time.Now()
+				})
+			}
+
+		case category.WarningHours > 0 && !ticket.Warned && inactive >= time.Duration(category.WarningHours)*time.Hour:
+			m.sendInactivityMessage(session, ticket, conf.Messages.InactiveWarning, closeAt)
+			m.updateTicket(ticket.ChannelID, func(ticket *Ticket) {
+				ticket.Reminded = true
+				ticket.Warned = true
+			})
+
+		case category.ReminderHours > 0 && !ticket.Reminded && !ticket.Warned && inactive >= time.Duration(category.ReminderHours)*time.Hour:
+			m.sendInactivityMessage(session, ticket, conf.Messages.InactiveReminder, closeAt)
+			m.updateTicket(ticket.ChannelID, func(ticket *Ticket) {
+				ticket.Reminded = true
+			})
+		}
+	}
+}
+
+func (m *TicketCog) sendInactivityMessage(session *discordgo.Session, ticket Ticket, message string, closeAt time.Time) {
+	if message == "" {
+		return
+	}
+
+	values := discord.TemplateValuesFor(session, ticket.GuildID, ticket.ChannelID, nil).SetUserID(ticket.UserID)
+	values["time"] = fmt.Sprintf("<t:%d:R>", closeAt.Unix())
+	if _, err := session.ChannelMessageSend(ticket.ChannelID, discord.ApplyTemplate(message, values)); err != nil {
+		config.Logger.Warnln("Failed to send inactivity message: ", err)
+	}
+}
+
+// deleteExpiredTickets deletes archived ticket threads older than the retention period of their guild.
+func (m *TicketCog) deleteExpiredTickets(session *discordgo.Session) {
+	m.StateMutex.Lock()
+	expired := []string{}
+	for channelID, ticket := range m.State.Tickets {
+		conf, ok := m.Config.Guilds[ticket.GuildID]
+		if !ok || ticket.Status != TicketArchived || conf.ArchiveRetentionDays <= 0 {
+			continue
+		}
+		if time.Since(ticket.ClosedAt) > time.Duration(conf.ArchiveRetentionDays)*24*time.Hour {
+			expired = append(expired, channelID)
+		}
+	}
+	m.StateMutex.Unlock()
+
+	for _, channelID := range expired {
+		config.Logger.Infoln("Deleting archived ticket", channelID, "after retention period")
+		if _, err := session.ChannelDelete(channelID); err != nil {
+			config.Logger.Errorln("Failed to delete archived ticket: ", err)
+			continue
+		}
+		m.setTicketStatus(channelID, TicketClosed)
+	}
+}
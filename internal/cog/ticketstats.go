@@ -0,0 +1,204 @@
+package cog
+
+import (
+	"fmt"
+	"phoenixbot/internal/config"
+	"sort"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const ticketStatsInterval = 7 * 24 * time.Hour
+
+// ticketStats are the statistics of one ticket type over a period.
+type ticketStats struct {
+	Opened    int
+	Closed    int
+	Outcomes  map[string]int
+	Responses []time.Duration // Time until the first staff message
+	Claims    []time.Duration // Time until the ticket was first claimed
+}
+
+func ticketStatsCommand() *discordgo.ApplicationCommand {
+	minDays := float64(1)
+	return &discordgo.ApplicationCommand{
+		Name:        "ticketstats",
+		Description: "Show ticket statistics",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "days",
+				Description: "Number of days to include, defaults to 7",
+				MinValue:    &minDays,
+				MaxValue:    365,
+			},
+		},
+	}
+}
+
+func (m *TicketCog) handleTicketStatsCommand(session *discordgo.Session, interaction *discordgo.Interaction) {
+
+	conf, ok := m.Config.Guilds[interaction.GuildID]
+	if !ok {
+		return
+	}
+
+	if !conf.isStaff(interaction.Member) {
+		sendNoPermission(session, interaction, conf)
+		return
+	}
+
+	days := int64(7)
+	for _, option := range interaction.ApplicationCommandData().Options {
+		if option.Name == "days" {
+			days = option.IntValue()
+		}
+	}
+
+	since := time.Now().Add(-time.Duration(days) * 24 * time.Hour)
+	embed := m.ticketStatsEmbed(conf, interaction.GuildID, since)
+
+	err := session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		config.Logger.Errorln("Failed to send ticket stats: ", err)
+	}
+}
+
+// postWeeklyStats posts the ticket summary of the past week to guilds that are due one.
+func (m *TicketCog) postWeeklyStats(session *discordgo.Session) {
+	for guildID, conf := range m.Config.Guilds {
+		if !config.IsGuildEnabled(guildID) || !conf.Enabled || conf.StatsChannel == "" {
+			continue
+		}
+
+		m.StateMutex.Lock()
+		last, ok := m.State.StatsReport[guildID]
+		if !ok {
+			// Start counting from the first run instead of posting right away
+			m.State.StatsReport[guildID] = time.Now()
+			m.saveState()
+		}
+		m.StateMutex.Unlock()
+
+		if !ok || time.Since(last) < ticketStatsInterval {
+			continue
+		}
+
+		embed := m.ticketStatsEmbed(conf, guildID, last)
+		embed.Title = "Weekly ticket summary"
+		if _, err := session.ChannelMessageSendEmbed(conf.StatsChannel, embed); err != nil {
+			config.Logger.Errorln("Failed to post weekly ticket summary: ", err)
+			continue
+		}
+
+		m.StateMutex.Lock()
+		m.State.StatsReport[guildID] = time.Now()
+		m.saveState()
+		m.StateMutex.Unlock()
+	}
+}
+
+// collectTicketStats groups the tickets of the guild opened after since by type.
+func (m *TicketCog) collectTicketStats(guildID string, since time.Time) map[string]*ticketStats {
+	m.StateMutex.Lock()
+	defer m.StateMutex.Unlock()
+
+	stats := make(map[string]*ticketStats)
+	for _, ticket := range m.State.Tickets {
+		if ticket.GuildID != guildID || ticket.CreatedAt.Before(since) {
+			continue
+		}
+
+		stat, ok := stats[ticket.Category]
+		if !ok {
+			stat = &ticketStats{Outcomes: make(map[string]int)}
+			stats[ticket.Category] = stat
+		}
+
+		stat.Opened++
+		if ticket.Status != TicketOpen {
+			stat.Closed++
+		}
+		if ticket.Outcome != "" {
+			stat.Outcomes[ticket.Outcome]++
+		}
+		if !ticket.FirstResponseAt.IsZero() {
+			stat.Responses = append(stat.Responses, ticket.FirstResponseAt.Sub(ticket.CreatedAt))
+		}
+		if !ticket.FirstClaimAt.IsZero() {
+			stat.Claims = append(stat.Claims, ticket.FirstClaimAt.Sub(ticket.CreatedAt))
+		}
+	}
+	return stats
+}
+
+func (m *TicketCog) ticketStatsEmbed(conf *TicketGuildConfig, guildID string, since time.Time) *discordgo.MessageEmbed {
+	stats := m.collectTicketStats(guildID, since)
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "Ticket statistics",
+		Description: fmt.Sprintf("Tickets opened since <t:%d:f>", since.Unix()),
+	}
+	if len(stats) == 0 {
+		embed.Description += "\nNo tickets were opened."
+		return embed
+	}
+
+	categories := make([]string, 0, len(stats))
+	for category := range stats {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	for _, categoryID := range categories {
+		stat := stats[categoryID]
+
+		name := categoryID
+		if category := conf.category(categoryID); category != nil && categoryID != "" {
+			name = category.Label
+		}
+
+		acceptance := "n/a"
+		if reviewed := stat.Outcomes[OutcomeAccepted] + stat.Outcomes[OutcomeDenied]; reviewed > 0 {
+			acceptance = fmt.Sprintf("%d%%", stat.Outcomes[OutcomeAccepted]*100/reviewed)
+		}
+
+		value := fmt.Sprintf("Opened %d, closed %d\n", stat.Opened, stat.Closed) +
+			fmt.Sprintf("Accepted %d, denied %d, abandoned %d, resolved %d\n",
+				stat.Outcomes[OutcomeAccepted], stat.Outcomes[OutcomeDenied], stat.Outcomes[OutcomeAbandoned], stat.Outcomes[OutcomeResolved]) +
+			fmt.Sprintf("Median first response %s\n", formatMedian(stat.Responses)) +
+			fmt.Sprintf("Median claim time %s\n", formatMedian(stat.Claims)) +
+			fmt.Sprintf("Acceptance rate %s", acceptance)
+
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: name, Value: value})
+
+		// Discord allows 25 fields per embed
+		if len(embed.Fields) == 25 {
+			break
+		}
+	}
+	return embed
+}
+
+// formatMedian returns the median of the durations rounded to minutes.
+func formatMedian(durations []time.Duration) string {
+	if len(durations) == 0 {
+		return "n/a"
+	}
+
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+	return median.Round(time.Minute).String()
+}
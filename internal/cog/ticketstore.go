@@ -0,0 +1,244 @@
+package cog
+
+import (
+	"phoenixbot/internal/config"
+	"phoenixbot/internal/discord"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	TicketOpen     = "open"
+	TicketClosed   = "closed"
+	TicketArchived = "archived" // Closed but the thread is kept until the retention period ends
+)
+
+// Ticket outcomes recorded when a ticket is closed
+const (
+	OutcomeAccepted  = "accepted"
+	OutcomeDenied    = "denied"
+	OutcomeAbandoned = "abandoned" // Closed for inactivity
+	OutcomeResolved  = "resolved"  // Closed without a review
+)
+
+// Ticket is the stored record of a ticket thread.
+type Ticket struct {
+	UserID    string `json:"UserID"`
+	ChannelID string `json:"ChannelID"` // Thread id of the ticket
+	ParentID  string `json:"ParentID"`  // Channel the thread was started in, or category of a ticket channel
+	GuildID   string `json:"GuildID"`
+	Category  string `json:"Category"`
+	Status    string `json:"Status"`
+	Number    int    `json:"Number"` // Per guild ticket number
+	Name      string `json:"Name"`   // Thread name without a status prefix
+
+	Mode string `json:"Mode,omitempty"` // TicketModeChannel for ticket channels, threads otherwise
+
+	Answers []TicketAnswer `json:"Answers,omitempty"` // Questionnaire answers for staff review
+
+	MessageID string    `json:"MessageID"` // Opening message with the ticket info embed
+	ClaimedBy string    `json:"ClaimedBy"` // Staff member handling the ticket
+	ClaimedAt time.Time `json:"ClaimedAt,omitempty"`
+
+	FirstClaimAt    time.Time `json:"FirstClaimAt,omitempty"`
+	FirstResponseAt time.Time `json:"FirstResponseAt,omitempty"` // First message from staff
+	Outcome         string    `json:"Outcome,omitempty"`
+
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+	ClosedAt  time.Time `json:"ClosedAt,omitempty"`
+
+	LastActivity time.Time `json:"LastActivity"` // Last message from a user, drives the inactivity timers
+	Reminded     bool      `json:"Reminded"`     // Inactivity reminder sent since the last activity
+	Warned       bool      `json:"Warned"`       // Auto-close warning sent since the last activity
+
+	TranscriptSent bool      `json:"TranscriptSent,omitempty"` // Transcript of the current close was sent
+	CloseFailedAt  time.Time `json:"CloseFailedAt,omitempty"`  // Last failed auto-close, retried after a backoff
+}
+
+type TicketAnswer struct {
+	Question string `json:"Question"`
+	Answer   string `json:"Answer"`
+}
+
+// getTicket returns a copy of the ticket of the channel.
+func (m *TicketCog) getTicket(channelID string) (Ticket, bool) {
+	m.StateMutex.Lock()
+	defer m.StateMutex.Unlock()
+
+	ticket, ok := m.State.Tickets[channelID]
+	if !ok {
+		return Ticket{}, false
+	}
+	return *ticket, true
+}
+
+// countOpenTickets returns how many open tickets of the category the user has in the guild.
+func (m *TicketCog) countOpenTickets(guildID, userID, category string) int {
+	m.StateMutex.Lock()
+	defer m.StateMutex.Unlock()
+
+	count := 0
+	for _, ticket := range m.State.Tickets {
+		if ticket.GuildID == guildID && ticket.UserID == userID && ticket.Category == category && ticket.Status == TicketOpen {
+			count++
+		}
+	}
+	return count
+}
+
+func (m *TicketCog) addTicket(ticket Ticket) {
+	m.StateMutex.Lock()
+	defer m.StateMutex.Unlock()
+
+	ticket.CreatedAt = time.Now()
+	ticket.UpdatedAt = ticket.CreatedAt
+	ticket.LastActivity = ticket.CreatedAt
+	m.State.Tickets[ticket.ChannelID] = &ticket
+	m.saveState()
+}
+
+// setTicketStatus updates the status of the ticket, returns false if there is no such ticket.
+func (m *TicketCog) setTicketStatus(channelID, status string) bool {
+	m.StateMutex.Lock()
+	defer m.StateMutex.Unlock()
+
+	ticket, ok := m.State.Tickets[channelID]
+	if !ok {
+		return false
+	}
+	if ticket.Status != status {
+		setStatus(ticket, status)
+		m.saveState()
+	}
+	return true
+}
+
+// nextTicketNumber returns the next ticket number of the guild.
+func (m *TicketCog) nextTicketNumber(guildID string) int {
+	m.StateMutex.Lock()
+	defer m.StateMutex.Unlock()
+
+	m.State.Counters[guildID]++
+	m.saveState()
+	return m.State.Counters[guildID]
+}
+
+// updateTicket applies update to the stored ticket and saves it, returns false if there is no such ticket.
+func (m *TicketCog) updateTicket(channelID string, update func(ticket *Ticket)) bool {
+	m.StateMutex.Lock()
+	defer m.StateMutex.Unlock()
+
+	ticket, ok := m.State.Tickets[channelID]
+	if !ok {
+		return false
+	}
+	update(ticket)
+	ticket.UpdatedAt = time.Now()
+	m.saveState()
+	return true
+}
+
+func setStatus(ticket *Ticket, status string) {
+	ticket.Status = status
+	ticket.UpdatedAt = time.Now()
+	if status == TicketOpen {
+		ticket.ClosedAt = time.Time{}
+		// Reopened tickets start a new inactivity period and get a new outcome
+		ticket.Outcome = ""
+		ticket.LastActivity = ticket.UpdatedAt
+		ticket.Reminded = false
+		ticket.Warned = false
+		ticket.TranscriptSent = false
+		ticket.CloseFailedAt = time.Time{}
+	} else if ticket.ClosedAt.IsZero() {
+		ticket.ClosedAt = ticket.UpdatedAt
+	}
+}
+
+// closedStatus is the status of a ticket whose thread got archived.
+func (m *TicketCog) closedStatus(guildID string) string {
+	if conf, ok := m.Config.Guilds[guildID]; ok && conf.CloseMode == TicketCloseArchive {
+		return TicketArchived
+	}
+	return TicketClosed
+}
+
+// reconcileTickets closes records of tickets whose thread was deleted or archived while offline.
+func (m *TicketCog) reconcileTickets(session *discordgo.Session) {
+	m.StateMutex.Lock()
+	channelIDs := []string{}
+	for channelID, ticket := range m.State.Tickets {
+		if ticket.Status != TicketClosed && config.IsGuildEnabled(ticket.GuildID) {
+			channelIDs = append(channelIDs, channelID)
+		}
+	}
+	m.StateMutex.Unlock()
+
+	for _, channelID := range channelIDs {
+		channel, err := session.Channel(channelID)
+		if discord.IsNotFound(err) {
+			config.Logger.Infoln("Ticket thread", channelID, "was deleted, closing ticket")
+			m.setTicketStatus(channelID, TicketClosed)
+			continue
+		}
+		if err != nil {
+			config.Logger.Warnln("Failed to fetch ticket thread", channelID, err)
+			continue
+		}
+		ticket, _ := m.getTicket(channelID)
+		if ticket.Status == TicketOpen && channel.ThreadMetadata != nil && channel.ThreadMetadata.Archived {
+			config.Logger.Infoln("Ticket thread", channelID, "was archived, closing ticket")
+			m.setTicketStatus(channelID, m.closedStatus(channel.GuildID))
+		}
+	}
+}
+
+func (m *TicketCog) handleThreadUpdate(session *discordgo.Session, thread *discordgo.ThreadUpdate) {
+	if thread.ThreadMetadata == nil {
+		return
+	}
+	ticket, ok := m.getTicket(thread.ID)
+	if !ok {
+		return
+	}
+
+	switch {
+	// Tickets archived by closing them already have their status
+	case thread.ThreadMetadata.Archived && ticket.Status == TicketOpen:
+		m.setTicketStatus(thread.ID, m.closedStatus(thread.GuildID))
+
+	// Threads auto-archived by discord are unarchived by new messages. Closed tickets are
+	// locked, those are only reopened by staff
+	case !thread.ThreadMetadata.Archived && !thread.ThreadMetadata.Locked && ticket.Status != TicketOpen:
+		config.Logger.Infoln("Ticket thread", thread.ID, "was unarchived, reopening ticket")
+		m.setTicketStatus(thread.ID, TicketOpen)
+	}
+}
+
+func (m *TicketCog) handleThreadDelete(session *discordgo.Session, thread *discordgo.ThreadDelete) {
+	m.setTicketStatus(thread.ID, TicketClosed)
+}
+
+func (m *TicketCog) handleChannelDelete(session *discordgo.Session, channel *discordgo.ChannelDelete) {
+	m.StateMutex.Lock()
+	defer m.StateMutex.Unlock()
+
+	changed := false
+	for _, ticket := range m.State.Tickets {
+		if ticket.Status == TicketClosed {
+			continue
+		}
+		// Deleting the apply channel removes every thread in it, deleting the category
+		// of ticket channels keeps the channels
+		threadParent := ticket.Mode != TicketModeChannel && ticket.ParentID == channel.ID
+		if ticket.ChannelID == channel.ID || threadParent {
+			setStatus(ticket, TicketClosed)
+			changed = true
+		}
+	}
+	if changed {
+		m.saveState()
+	}
+}
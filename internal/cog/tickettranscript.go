@@ -0,0 +1,78 @@
+package cog
+
+import (
+	"fmt"
+	"phoenixbot/internal/config"
+	"phoenixbot/internal/discord"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// sendTranscript posts the ticket history to the transcript channel and optionally to the owner.
+func (m *TicketCog) sendTranscript(session *discordgo.Session, ticket Ticket, closedBy string) {
+
+	conf, ok := m.Config.Guilds[ticket.GuildID]
+	if !ok || (conf.TranscriptChannel == "" && !conf.DMTranscript) {
+		return
+	}
+
+	channel, err := session.Channel(ticket.ChannelID)
+	if err != nil {
+		config.Logger.Errorln("Failed to get ticket channel for transcript: ", err)
+		return
+	}
+
+	messages, err := discord.FetchChannelMessages(session, ticket.ChannelID)
+	if err != nil {
+		config.Logger.Errorln("Failed to fetch ticket messages for transcript: ", err)
+		return
+	}
+
+	text := discord.RenderTranscriptText(channel, messages)
+	html, err := discord.RenderTranscriptHTML(channel, messages)
+	if err != nil {
+		config.Logger.Errorln("Failed to render html transcript: ", err)
+		return
+	}
+
+	// Readers are consumed on send, so every message gets its own files
+	files := func() []*discordgo.File {
+		return []*discordgo.File{
+			{Name: channel.Name + ".html", ContentType: "text/html", Reader: strings.NewReader(html)},
+			{Name: channel.Name + ".txt", ContentType: "text/plain", Reader: strings.NewReader(text)},
+		}
+	}
+
+	if conf.TranscriptChannel != "" {
+		owner := "unknown"
+		if ticket.UserID != "" {
+			owner = "<@" + ticket.UserID + ">"
+		}
+
+		_, err := session.ChannelMessageSendComplex(conf.TranscriptChannel, &discordgo.MessageSend{
+			Content:         fmt.Sprintf("Transcript of **%s** opened by %s, closed by <@%s>", channel.Name, owner, closedBy),
+			Files:           files(),
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+		if err != nil {
+			config.Logger.Errorln("Failed to post transcript: ", err)
+		}
+	}
+
+	if conf.DMTranscript && ticket.UserID != "" {
+		dm, err := session.UserChannelCreate(ticket.UserID)
+		if err != nil {
+			config.Logger.Warnln("Failed to open dm for transcript: ", err)
+			return
+		}
+
+		_, err = session.ChannelMessageSendComplex(dm.ID, &discordgo.MessageSend{
+			Content: fmt.Sprintf("Here is the transcript of your ticket **%s**.", channel.Name),
+			Files:   files(),
+		})
+		if err != nil {
+			config.Logger.Warnln("Failed to dm transcript: ", err)
+		}
+	}
+}
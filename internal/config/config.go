@@ -19,6 +19,8 @@ type configuration struct {
 	DiscordToken string
 
 	Guilds map[string]bool `json:"Guilds"`
+
+	SyncGlobalCommands bool `json:"SyncGlobalCommands"` // Also overwrite the global application commands on startup
 }
 
 var Configuration *configuration
@@ -1,7 +1,9 @@
 package discord
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"phoenixbot/internal/config"
 	"time"
 
@@ -11,14 +13,17 @@ import (
 type ClearMessagesOnChannelOptions struct {
 	Blacklist []string // User ids to exclude
 	Whitelist []string // User ids to include
+	Keep      []string // Message ids to exclude
 	Before    string   // Message id to fetch messages before
 	After     string   // Message id to fetch messages after
 	Limit     int
 }
 
 type MessageData struct {
-	Content string     `json:"Content,omitempty"`
-	Embed   *EmbedData `json:"Embed,omitempty"`
+	Content string       `json:"Content,omitempty"`
+	Embed   *EmbedData   `json:"Embed,omitempty"`
+	Embeds  []*EmbedData `json:"Embeds,omitempty"`  // Sent after Embed, at most 10 in total
+	Buttons []ButtonData `json:"Buttons,omitempty"` // Link buttons below the message
 }
 
 type EmbedData struct {
@@ -29,6 +34,10 @@ type EmbedData struct {
 	Footer      Footer `json:"Footer,omitempty"`
 	Image       string `json:"Image,omitempty"`
 	Thumbnail   string `json:"Thumbnail,omitempty"`
+
+	Author    Author       `json:"Author,omitempty"`
+	Fields    []EmbedField `json:"Fields,omitempty"`
+	Timestamp string       `json:"Timestamp,omitempty"` // "now" or an RFC 3339 time
 }
 
 type Footer struct {
@@ -36,6 +45,24 @@ type Footer struct {
 	IconURL string `json:"Icon_url,omitempty"`
 }
 
+type Author struct {
+	Name    string `json:"Name,omitempty"`
+	URL     string `json:"Url,omitempty"`
+	IconURL string `json:"Icon_url,omitempty"`
+}
+
+type EmbedField struct {
+	Name   string `json:"Name"`
+	Value  string `json:"Value"`
+	Inline bool   `json:"Inline,omitempty"`
+}
+
+type ButtonData struct {
+	Label string `json:"Label"`
+	URL   string `json:"Url"`
+	Emoji string `json:"Emoji,omitempty"`
+}
+
 func SendReplyMessageTimed(session *discordgo.Session, channelID, messageID, content string, timeout time.Duration) error {
 	msg, err := session.ChannelMessageSendReply(channelID, content, &discordgo.MessageReference{
 		MessageID: messageID,
@@ -57,24 +84,34 @@ func SendReplyMessageTimed(session *discordgo.Session, channelID, messageID, con
 }
 
 func SendInteractionResponse(session *discordgo.Session, interaction *discordgo.Interaction, msg *discordgo.MessageSend) error {
-	embeds := []*discordgo.MessageEmbed{}
+	embeds := msg.Embeds
 	if msg.Embed != nil {
-		embeds = append(embeds, msg.Embed)
-	} else {
-		embeds = nil
+		embeds = append([]*discordgo.MessageEmbed{msg.Embed}, embeds...)
 	}
 
 	response := &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content: msg.Content,
-			Embeds:  embeds,
+			Content:         msg.Content,
+			Embeds:          embeds,
+			Components:      msg.Components,
+			AllowedMentions: msg.AllowedMentions,
 		},
 	}
 
 	return session.InteractionRespond(interaction, response)
 }
 
+func SendEphemeralResponse(session *discordgo.Session, interaction *discordgo.Interaction, content string) error {
+	return session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
 func GetUserVoiceState(s *discordgo.Session, guildID, userID string) *discordgo.VoiceState {
 	guild, err := s.State.Guild(guildID)
 	if err != nil {
@@ -90,6 +127,71 @@ func GetUserVoiceState(s *discordgo.Session, guildID, userID string) *discordgo.
 	return nil
 }
 
+// CountVoiceChannelUsers returns how many non-bot users are connected to the voice channel.
+func CountVoiceChannelUsers(s *discordgo.Session, guildID, channelID string) int {
+	guild, err := s.State.Guild(guildID)
+	if err != nil {
+		config.Logger.Errorln("Failed to get guild:", err)
+		return 0
+	}
+
+	count := 0
+	for _, vs := range guild.VoiceStates {
+		if vs.ChannelID != channelID {
+			continue
+		}
+		if vs.Member != nil && vs.Member.User != nil && vs.Member.User.Bot {
+			continue
+		}
+		if s.State.User != nil && vs.UserID == s.State.User.ID {
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// MemberHasAnyRole reports whether the member has at least one of the roles (name and ID).
+func MemberHasAnyRole(member *discordgo.Member, roles map[string]string) bool {
+	if member == nil {
+		return false
+	}
+
+	for _, roleID := range roles {
+		for _, memberRole := range member.Roles {
+			if memberRole == roleID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GuildMembersWithAnyRole lists every member of the guild that has at least one of the roles.
+func GuildMembersWithAnyRole(session *discordgo.Session, guildID string, roles map[string]string) ([]*discordgo.Member, error) {
+	found := []*discordgo.Member{}
+	if len(roles) == 0 {
+		return found, nil
+	}
+
+	after := ""
+	for {
+		members, err := session.GuildMembers(guildID, after, 1000)
+		if err != nil {
+			return found, err
+		}
+		for _, member := range members {
+			if !member.User.Bot && MemberHasAnyRole(member, roles) {
+				found = append(found, member)
+			}
+		}
+		if len(members) < 1000 {
+			return found, nil
+		}
+		after = members[len(members)-1].User.ID
+	}
+}
+
 func ClearMessagesOnChannel(session *discordgo.Session, channelID string, options *ClearMessagesOnChannelOptions) error {
 	if options == nil {
 		options = &ClearMessagesOnChannelOptions{}
@@ -115,10 +217,19 @@ func ClearMessagesOnChannel(session *discordgo.Session, channelID string, option
 		whitelistMap[id] = struct{}{}
 	}
 
+	keepMap := make(map[string]struct{})
+	for _, id := range options.Keep {
+		keepMap[id] = struct{}{}
+	}
+
 	var messagesToDelete []string
 	for _, msg := range messages {
 		authorID := msg.Author.ID
 
+		if _, keep := keepMap[msg.ID]; keep {
+			continue
+		}
+
 		if _, blacklisted := blacklistMap[authorID]; blacklisted {
 			continue
 		}
@@ -146,20 +257,125 @@ func ClearMessagesOnChannel(session *discordgo.Session, channelID string, option
 	return nil
 }
 
-func CreateMessageSend(message MessageData) (*discordgo.MessageSend, error) {
-	mess := &discordgo.MessageSend{}
+// IsNotFound reports whether err is a discord api error for a missing message, channel or member.
+func IsNotFound(err error) bool {
+	var restErr *discordgo.RESTError
+	return errors.As(err, &restErr) && restErr.Response != nil && restErr.Response.StatusCode == http.StatusNotFound
+}
 
-	embed, err := CreateEmbed(message.Embed)
+// ClearStaleOwnMessages deletes the bot's own messages in the channel except keepID,
+// and reports whether keepID is still a message of the bot that can be edited.
+func ClearStaleOwnMessages(session *discordgo.Session, channelID, keepID string) bool {
+	botID := session.State.User.ID
+
+	kept := false
+	if keepID != "" {
+		msg, err := session.ChannelMessage(channelID, keepID)
+		kept = err == nil && msg.Author != nil && msg.Author.ID == botID
+	}
+
+	err := ClearMessagesOnChannel(session, channelID, &ClearMessagesOnChannelOptions{
+		Whitelist: []string{botID},
+		Keep:      []string{keepID},
+	})
 	if err != nil {
-		return nil, err
+		config.Logger.Warnf("Failed to clear stale messages in channel %s: %v", channelID, err)
+	}
+	return kept
+}
+
+// MessageEditFromSend builds an edit replacing the content, embeds and components of an existing message.
+func MessageEditFromSend(channelID, messageID string, msg *discordgo.MessageSend) *discordgo.MessageEdit {
+	embeds := msg.Embeds
+	if msg.Embed != nil {
+		embeds = append([]*discordgo.MessageEmbed{msg.Embed}, embeds...)
+	}
+	if embeds == nil {
+		embeds = []*discordgo.MessageEmbed{}
+	}
+
+	components := msg.Components
+	if components == nil {
+		components = []discordgo.MessageComponent{}
 	}
 
-	mess.Embed = embed
-	mess.Content = message.Content
+	return &discordgo.MessageEdit{
+		ID:         messageID,
+		Channel:    channelID,
+		Content:    &msg.Content,
+		Embeds:     &embeds,
+		Components: &components,
+	}
+}
+
+// CreateMessageSend builds the message and fills in its placeholders with the values.
+// Callers adding their own components should append to Components.
+func CreateMessageSend(message MessageData, values TemplateValues) (*discordgo.MessageSend, error) {
+	mess := &discordgo.MessageSend{}
+
+	embedsData := message.Embeds
+	if message.Embed != nil {
+		embedsData = append([]*EmbedData{message.Embed}, embedsData...)
+	}
+	for _, embedData := range embedsData {
+		embed, err := CreateEmbed(embedData)
+		if err != nil {
+			return nil, err
+		}
+		if embed == nil {
+			continue
+		}
+		ApplyEmbedTemplate(embed, values)
+		mess.Embeds = append(mess.Embeds, embed)
+	}
+
+	mess.Content = ApplyTemplate(message.Content, values)
+	mess.Components = createLinkButtons(message.Buttons)
 
 	return mess, nil
 }
 
+// Discord component limits of a message
+const (
+	MaxButtonsPerRow = 5
+	MaxActionRows    = 5
+)
+
+// RowCount returns how many action rows the buttons take.
+func RowCount(buttons int) int {
+	return (buttons + MaxButtonsPerRow - 1) / MaxButtonsPerRow
+}
+
+// ButtonRows puts the buttons in action rows of MaxButtonsPerRow buttons.
+func ButtonRows(buttons []discordgo.MessageComponent) []discordgo.MessageComponent {
+	rows := []discordgo.MessageComponent{}
+	for len(buttons) > MaxButtonsPerRow {
+		rows = append(rows, discordgo.ActionsRow{Components: buttons[:MaxButtonsPerRow:MaxButtonsPerRow]})
+		buttons = buttons[MaxButtonsPerRow:]
+	}
+	if len(buttons) > 0 {
+		rows = append(rows, discordgo.ActionsRow{Components: buttons})
+	}
+	return rows
+}
+
+func createLinkButtons(buttonsData []ButtonData) []discordgo.MessageComponent {
+	buttons := []discordgo.MessageComponent{}
+	for _, data := range buttonsData {
+		button := discordgo.Button{
+			Label: data.Label,
+			Style: discordgo.LinkButton,
+			URL:   data.URL,
+		}
+		if data.Emoji != "" {
+			button.Emoji = &discordgo.ComponentEmoji{Name: data.Emoji}
+		}
+
+		buttons = append(buttons, button)
+	}
+	return ButtonRows(buttons)
+}
+
 func CreateEmbed(message *EmbedData) (*discordgo.MessageEmbed, error) {
 
 	if message == nil {
@@ -178,7 +394,11 @@ func CreateEmbed(message *EmbedData) (*discordgo.MessageEmbed, error) {
 		embed.URL = message.URL
 	}
 	if message.Color != "" {
-		embed.Color = parseHexColor(message.Color)
+		color, err := parseColor(message.Color)
+		if err != nil {
+			return nil, err
+		}
+		embed.Color = color
 	}
 	if message.Footer.Text != "" || message.Footer.IconURL != "" {
 		embed.Footer = &discordgo.MessageEmbedFooter{
@@ -192,15 +412,29 @@ func CreateEmbed(message *EmbedData) (*discordgo.MessageEmbed, error) {
 	if message.Image != "" {
 		embed.Image = &discordgo.MessageEmbedImage{URL: message.Image}
 	}
+	if message.Author.Name != "" {
+		embed.Author = &discordgo.MessageEmbedAuthor{
+			Name:    message.Author.Name,
+			URL:     message.Author.URL,
+			IconURL: message.Author.IconURL,
+		}
+	}
+	for _, field := range message.Fields {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   field.Name,
+			Value:  field.Value,
+			Inline: field.Inline,
+		})
+	}
+	if message.Timestamp == "now" {
+		embed.Timestamp = time.Now().Format(time.RFC3339)
+	} else if message.Timestamp != "" {
+		timestamp, err := time.Parse(time.RFC3339, message.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid embed timestamp %q: %w", message.Timestamp, err)
+		}
+		embed.Timestamp = timestamp.Format(time.RFC3339)
+	}
 
 	return embed, nil
 }
-
-func parseHexColor(color string) int {
-	var parsedColor int
-	_, err := fmt.Sscanf(color, "0x%x", &parsedColor)
-	if err != nil {
-		return 0xFFFFFF // Default to white if parsing fails
-	}
-	return parsedColor
-}
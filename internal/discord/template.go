@@ -0,0 +1,112 @@
+package discord
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// TemplateValues maps placeholder names to their replacements. A placeholder is written
+// as {name} in configured messages, {{ and }} produce literal braces.
+type TemplateValues map[string]string
+
+// TemplateValuesFor returns the common placeholders for a message sent in the channel of the guild.
+// Placeholders of the user are only set when a user is given.
+//
+//	{guild} {member_count} {channel} {date} {user} {user_mention} {user_id}
+func TemplateValuesFor(session *discordgo.Session, guildID, channelID string, user *discordgo.User) TemplateValues {
+	values := TemplateValues{
+		"date": time.Now().Format("2006-01-02"),
+	}
+
+	if guildID != "" {
+		guild, err := session.State.Guild(guildID)
+		if err != nil {
+			guild, err = session.GuildWithCounts(guildID)
+		}
+		if err == nil {
+			values["guild"] = guild.Name
+			count := guild.MemberCount
+			if count == 0 {
+				count = guild.ApproximateMemberCount
+			}
+			values["member_count"] = strconv.Itoa(count)
+		}
+	}
+
+	if channelID != "" {
+		values["channel"] = "<#" + channelID + ">"
+	}
+
+	if user != nil {
+		values.SetUser(user)
+	}
+	return values
+}
+
+// SetUser sets the placeholders of the user.
+func (values TemplateValues) SetUser(user *discordgo.User) TemplateValues {
+	values["user"] = user.Username
+	return values.SetUserID(user.ID)
+}
+
+// SetUserID sets the placeholders that only need the id of the user.
+func (values TemplateValues) SetUserID(userID string) TemplateValues {
+	values["user_id"] = userID
+	values["user_mention"] = "<@" + userID + ">"
+	return values
+}
+
+// ApplyTemplate replaces the placeholders in text. Unknown placeholders are left as they are.
+func ApplyTemplate(text string, values TemplateValues) string {
+	if !strings.ContainsAny(text, "{}") {
+		return text
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+
+		// Doubled braces are escapes for a literal brace
+		if (c == '{' || c == '}') && i+1 < len(text) && text[i+1] == c {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+
+		if c == '{' {
+			end := strings.IndexAny(text[i+1:], "{}")
+			if end >= 0 && text[i+1+end] == '}' {
+				if value, ok := values[text[i+1:i+1+end]]; ok {
+					b.WriteString(value)
+					i += end + 1
+					continue
+				}
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// ApplyEmbedTemplate replaces the placeholders in the texts of the embed.
+func ApplyEmbedTemplate(embed *discordgo.MessageEmbed, values TemplateValues) {
+	if embed == nil {
+		return
+	}
+
+	embed.Title = ApplyTemplate(embed.Title, values)
+	embed.Description = ApplyTemplate(embed.Description, values)
+	if embed.Footer != nil {
+		embed.Footer.Text = ApplyTemplate(embed.Footer.Text, values)
+	}
+	if embed.Author != nil {
+		embed.Author.Name = ApplyTemplate(embed.Author.Name, values)
+	}
+	for _, field := range embed.Fields {
+		field.Name = ApplyTemplate(field.Name, values)
+		field.Value = ApplyTemplate(field.Value, values)
+	}
+}
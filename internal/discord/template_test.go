@@ -0,0 +1,92 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestApplyTemplate(t *testing.T) {
+	values := TemplateValues{
+		"user":  "steve",
+		"guild": "Phoenix",
+		"empty": "",
+		"brace": "{user}",
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"no placeholders", "Hello there", "Hello there"},
+		{"known placeholder", "Hello {user}", "Hello steve"},
+		{"several placeholders", "{user} joined {guild}", "steve joined Phoenix"},
+		{"repeated placeholder", "{user}{user}", "stevesteve"},
+		{"empty value", "a{empty}b", "ab"},
+		{"unknown placeholder", "Hello {nobody}", "Hello {nobody}"},
+		{"unknown next to known", "{nobody} {user}", "{nobody} steve"},
+		{"escaped braces", "{{user}}", "{user}"},
+		{"escaped open brace", "a {{ b", "a { b"},
+		{"escaped close brace", "a }} b", "a } b"},
+		{"escape around placeholder", "{{{user}}}", "{steve}"},
+		{"unclosed brace", "Hello {user", "Hello {user"},
+		{"unclosed brace before placeholder", "{ {user}", "{ steve"},
+		{"lone close brace", "a } b", "a } b"},
+		{"empty placeholder", "a {} b", "a {} b"},
+		{"value with braces is not expanded", "{brace}", "{user}"},
+		{"multibyte text", "Grüße {user} ✨", "Grüße steve ✨"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ApplyTemplate(test.text, values); got != test.want {
+				t.Errorf("ApplyTemplate(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestApplyEmbedTemplate(t *testing.T) {
+	values := TemplateValues{"user": "steve", "guild": "Phoenix"}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "Welcome {user}",
+		Description: "to {guild}",
+		Footer:      &discordgo.MessageEmbedFooter{Text: "{guild} staff"},
+		Author:      &discordgo.MessageEmbedAuthor{Name: "{user}"},
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "{user}", Value: "{{literal}}"},
+			{Name: "Server", Value: "{guild}"},
+		},
+	}
+	ApplyEmbedTemplate(embed, values)
+
+	checks := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"title", embed.Title, "Welcome steve"},
+		{"description", embed.Description, "to Phoenix"},
+		{"footer", embed.Footer.Text, "Phoenix staff"},
+		{"author", embed.Author.Name, "steve"},
+		{"first field name", embed.Fields[0].Name, "steve"},
+		{"first field value", embed.Fields[0].Value, "{literal}"},
+		{"second field name", embed.Fields[1].Name, "Server"},
+		{"second field value", embed.Fields[1].Value, "Phoenix"},
+	}
+	for _, check := range checks {
+		if check.got != check.want {
+			t.Errorf("%s = %q, want %q", check.name, check.got, check.want)
+		}
+	}
+
+	// Embeds without footer or author are left alone
+	bare := &discordgo.MessageEmbed{Title: "{user}"}
+	ApplyEmbedTemplate(bare, values)
+	if bare.Title != "steve" || bare.Footer != nil || bare.Author != nil {
+		t.Errorf("unexpected bare embed %+v", bare)
+	}
+	ApplyEmbedTemplate(nil, values)
+}
@@ -0,0 +1,130 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// FetchChannelMessages returns every message of the channel, oldest first.
+func FetchChannelMessages(session *discordgo.Session, channelID string) ([]*discordgo.Message, error) {
+	messages := []*discordgo.Message{}
+	before := ""
+	for {
+		batch, err := session.ChannelMessages(channelID, 100, before, "", "")
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, batch...)
+		if len(batch) < 100 {
+			break
+		}
+		before = batch[len(batch)-1].ID
+	}
+
+	// Discord returns newest first
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+// RenderTranscriptText renders the messages of a channel as plain text.
+func RenderTranscriptText(channel *discordgo.Channel, messages []*discordgo.Message) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Transcript of #%s (%s)\n", channel.Name, channel.ID)
+	fmt.Fprintf(&b, "Generated %s, %d messages\n\n", time.Now().UTC().Format(time.RFC1123), len(messages))
+
+	for _, msg := range messages {
+		fmt.Fprintf(&b, "[%s] %s (%s): %s\n", msg.Timestamp.UTC().Format("2006-01-02 15:04:05"), authorName(msg), authorID(msg), msg.Content)
+		for _, attachment := range msg.Attachments {
+			fmt.Fprintf(&b, "    Attachment: %s\n", attachment.URL)
+		}
+		for _, embed := range msg.Embeds {
+			fmt.Fprintf(&b, "    Embed: %s\n", embed.Title)
+			if embed.Description != "" {
+				fmt.Fprintf(&b, "        %s\n", strings.ReplaceAll(embed.Description, "\n", "\n        "))
+			}
+			for _, field := range embed.Fields {
+				fmt.Fprintf(&b, "        %s: %s\n", field.Name, strings.ReplaceAll(field.Value, "\n", "\n        "))
+			}
+		}
+	}
+	return b.String()
+}
+
+var transcriptTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"author":   authorName,
+	"authorID": authorID,
+	"time": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04:05")
+	},
+	"color": func(c int) string {
+		return fmt.Sprintf("#%06x", c)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Transcript of #{{.Channel.Name}}</title>
+<style>
+body { background: #313338; color: #dbdee1; font-family: sans-serif; margin: 2em; }
+.message { margin-bottom: 1em; }
+.author { color: #fff; font-weight: bold; }
+.time { color: #949ba4; font-size: 0.8em; margin-left: 0.5em; }
+.content { white-space: pre-wrap; }
+.embed { border-left: 4px solid #1e1f22; background: #2b2d31; padding: 0.5em 1em; margin-top: 0.3em; max-width: 600px; }
+.embed .title { font-weight: bold; }
+.field .name { font-weight: bold; margin-top: 0.3em; }
+a { color: #00a8fc; }
+</style>
+</head>
+<body>
+<h1>#{{.Channel.Name}}</h1>
+<p>{{len .Messages}} messages, generated {{time .Generated}} UTC</p>
+{{range .Messages}}<div class="message">
+<span class="author" title="{{authorID .}}">{{author .}}</span><span class="time">{{time .Timestamp}}</span>
+<div class="content">{{.Content}}</div>
+{{range .Attachments}}<div class="attachment"><a href="{{.URL}}">{{.Filename}}</a></div>
+{{end}}{{range .Embeds}}<div class="embed" style="border-color: {{color .Color}}">
+{{if .Title}}<div class="title">{{.Title}}</div>{{end}}
+{{if .Description}}<div class="content">{{.Description}}</div>{{end}}
+{{range .Fields}}<div class="field"><div class="name">{{.Name}}</div><div class="content">{{.Value}}</div></div>{{end}}
+</div>
+{{end}}</div>
+{{end}}
+</body>
+</html>
+`))
+
+// RenderTranscriptHTML renders the messages of a channel as a standalone html page.
+func RenderTranscriptHTML(channel *discordgo.Channel, messages []*discordgo.Message) (string, error) {
+	var b bytes.Buffer
+	err := transcriptTemplate.Execute(&b, struct {
+		Channel   *discordgo.Channel
+		Messages  []*discordgo.Message
+		Generated time.Time
+	}{channel, messages, time.Now()})
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func authorName(msg *discordgo.Message) string {
+	if msg.Author == nil {
+		return "Unknown"
+	}
+	return msg.Author.Username
+}
+
+func authorID(msg *discordgo.Message) string {
+	if msg.Author == nil {
+		return ""
+	}
+	return msg.Author.ID
+}
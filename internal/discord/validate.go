@@ -0,0 +1,276 @@
+package discord
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Discord message limits
+const (
+	maxContentLength     = 2000
+	maxEmbeds            = 10
+	maxEmbedTotal        = 6000
+	maxTitleLength       = 256
+	maxDescriptionLength = 4096
+	maxFields            = 25
+	maxFieldNameLength   = 256
+	maxFieldValueLength  = 1024
+	maxFooterLength      = 2048
+	maxAuthorLength      = 256
+	maxButtons           = 25
+	maxButtonLabelLength = 80
+)
+
+var namedColors = map[string]int{
+	"white":   0xFFFFFF,
+	"black":   0x000000,
+	"red":     0xED4245,
+	"green":   0x57F287,
+	"blue":    0x3498DB,
+	"blurple": 0x5865F2,
+	"yellow":  0xFEE75C,
+	"orange":  0xE67E22,
+	"gold":    0xF1C40F,
+	"purple":  0x9B59B6,
+	"pink":    0xEB459E,
+	"aqua":    0x1ABC9C,
+	"grey":    0x95A5A6,
+	"gray":    0x95A5A6,
+}
+
+// ValidationError is a problem with a configured message, Path is the JSON path of the value.
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidateMessages finds every MessageData in the loaded config and checks it against the discord limits.
+func ValidateMessages(config interface{}) []error {
+	errs := []error{}
+	validateValue(reflect.ValueOf(config), "", &errs)
+	return errs
+}
+
+func validateValue(value reflect.Value, path string, errs *[]error) {
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !value.IsNil() {
+			validateValue(value.Elem(), path, errs)
+		}
+
+	case reflect.Struct:
+		if message, ok := value.Interface().(MessageData); ok {
+			*errs = append(*errs, message.Validate(path)...)
+			return
+		}
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			validateValue(value.Field(i), joinPath(path, jsonName(field)), errs)
+		}
+
+	case reflect.Map:
+		// Sorted so problems are reported in the same order every time
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			validateValue(value.MapIndex(key), joinPath(path, fmt.Sprint(key.Interface())), errs)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			validateValue(value.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// Validate checks the message against the discord limits, path is used in the returned errors.
+func (message MessageData) Validate(path string) []error {
+	errs := []error{}
+	fail := func(path, format string, args ...interface{}) {
+		errs = append(errs, &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	checkLength := func(path, text string, max int) {
+		if length := utf8.RuneCountInString(text); length > max {
+			fail(path, "is %d characters long, the limit is %d", length, max)
+		}
+	}
+
+	checkLength(joinPath(path, "Content"), message.Content, maxContentLength)
+
+	embeds := []*EmbedData{}
+	embedPaths := []string{}
+	if message.Embed != nil {
+		embeds = append(embeds, message.Embed)
+		embedPaths = append(embedPaths, joinPath(path, "Embed"))
+	}
+	for i, embed := range message.Embeds {
+		if embed != nil {
+			embeds = append(embeds, embed)
+			embedPaths = append(embedPaths, fmt.Sprintf("%s[%d]", joinPath(path, "Embeds"), i))
+		}
+	}
+	if len(embeds) > maxEmbeds {
+		fail(joinPath(path, "Embeds"), "has %d embeds, the limit is %d", len(embeds), maxEmbeds)
+	}
+
+	total := 0
+	for i, embed := range embeds {
+		embedPath := embedPaths[i]
+
+		checkLength(joinPath(embedPath, "Title"), embed.Title, maxTitleLength)
+		checkLength(joinPath(embedPath, "Description"), embed.Description, maxDescriptionLength)
+		checkLength(joinPath(embedPath, "Footer.Text"), embed.Footer.Text, maxFooterLength)
+		checkLength(joinPath(embedPath, "Author.Name"), embed.Author.Name, maxAuthorLength)
+
+		if len(embed.Fields) > maxFields {
+			fail(joinPath(embedPath, "Fields"), "has %d fields, the limit is %d", len(embed.Fields), maxFields)
+		}
+		for j, field := range embed.Fields {
+			fieldPath := fmt.Sprintf("%s[%d]", joinPath(embedPath, "Fields"), j)
+			if field.Name == "" || field.Value == "" {
+				fail(fieldPath, "fields need both a Name and a Value")
+			}
+			checkLength(joinPath(fieldPath, "Name"), field.Name, maxFieldNameLength)
+			checkLength(joinPath(fieldPath, "Value"), field.Value, maxFieldValueLength)
+			total += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+		}
+
+		total += utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description) +
+			utf8.RuneCountInString(embed.Footer.Text) + utf8.RuneCountInString(embed.Author.Name)
+
+		for name, link := range map[string]string{
+			"Url":             embed.URL,
+			"Image":           embed.Image,
+			"Thumbnail":       embed.Thumbnail,
+			"Footer.Icon_url": embed.Footer.IconURL,
+			"Author.Url":      embed.Author.URL,
+			"Author.Icon_url": embed.Author.IconURL,
+		} {
+			if err := validateURL(link); err != nil {
+				fail(joinPath(embedPath, name), "%v", err)
+			}
+		}
+
+		if embed.Color != "" {
+			if _, err := parseColor(embed.Color); err != nil {
+				fail(joinPath(embedPath, "Color"), "%v", err)
+			}
+		}
+
+		if embed.Timestamp != "" && embed.Timestamp != "now" {
+			if _, err := time.Parse(time.RFC3339, embed.Timestamp); err != nil {
+				fail(joinPath(embedPath, "Timestamp"), "%q is not \"now\" or an RFC 3339 time", embed.Timestamp)
+			}
+		}
+	}
+	if total > maxEmbedTotal {
+		fail(joinPath(path, "Embeds"), "embeds have %d characters in total, the limit is %d", total, maxEmbedTotal)
+	}
+
+	if len(message.Buttons) > maxButtons {
+		fail(joinPath(path, "Buttons"), "has %d buttons, the limit is %d", len(message.Buttons), maxButtons)
+	}
+	for i, button := range message.Buttons {
+		buttonPath := fmt.Sprintf("%s[%d]", joinPath(path, "Buttons"), i)
+		if button.Label == "" && button.Emoji == "" {
+			fail(buttonPath, "buttons need a Label or an Emoji")
+		}
+		checkLength(joinPath(buttonPath, "Label"), button.Label, maxButtonLabelLength)
+		if button.URL == "" {
+			fail(joinPath(buttonPath, "Url"), "link buttons need a Url")
+		} else if err := validateURL(button.URL); err != nil {
+			fail(joinPath(buttonPath, "Url"), "%v", err)
+		}
+	}
+
+	return errs
+}
+
+// ValidateReservedRows checks that the link buttons of the message fit below the given
+// number of action rows the sender adds itself.
+func (message MessageData) ValidateReservedRows(path string, reserved int) error {
+	rows := RowCount(len(message.Buttons))
+	if reserved+rows > MaxActionRows {
+		return &ValidationError{
+			Path:    joinPath(path, "Buttons"),
+			Message: fmt.Sprintf("has %d rows of link buttons and the bot adds %d, the limit is %d rows", rows, reserved, MaxActionRows),
+		}
+	}
+	return nil
+}
+
+// validateURL accepts empty strings and absolute http, https and attachment urls.
+func validateURL(link string) error {
+	if link == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid url", link)
+	}
+	switch parsed.Scheme {
+	case "http", "https":
+		if parsed.Host == "" {
+			return fmt.Errorf("%q has no host", link)
+		}
+	case "attachment":
+	default:
+		return fmt.Errorf("%q must start with http:// or https://", link)
+	}
+	return nil
+}
+
+// parseColor parses hex colors written as 0xRRGGBB or #RRGGBB, and named colors like "red".
+func parseColor(color string) (int, error) {
+	color = strings.TrimSpace(color)
+	if named, ok := namedColors[strings.ToLower(color)]; ok {
+		return named, nil
+	}
+
+	hex := ""
+	switch {
+	case strings.HasPrefix(color, "0x"), strings.HasPrefix(color, "0X"):
+		hex = color[2:]
+	case strings.HasPrefix(color, "#"):
+		hex = color[1:]
+	default:
+		return 0, fmt.Errorf("%q is not a color, use 0xRRGGBB, #RRGGBB or a color name", color)
+	}
+
+	parsed, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return 0, fmt.Errorf("%q is not a valid hex color, use 6 hex digits", color)
+	}
+	return int(parsed), nil
+}
@@ -0,0 +1,167 @@
+package discord
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		color   string
+		want    int
+		wantErr bool
+	}{
+		{"0x00AAFF", 0x00AAFF, false},
+		{"0X00aaff", 0x00AAFF, false},
+		{"#FF0000", 0xFF0000, false},
+		{" #ff0000 ", 0xFF0000, false},
+		{"red", 0xED4245, false},
+		{"Blurple", 0x5865F2, false},
+		{"0xFFF", 0, true},
+		{"#12345G", 0, true},
+		{"0x1234567", 0, true},
+		{"FF0000", 0, true},
+		{"rainbow", 0, true},
+		{"", 0, true},
+	}
+
+	for _, test := range tests {
+		got, err := parseColor(test.color)
+		if (err != nil) != test.wantErr {
+			t.Errorf("parseColor(%q) error = %v, want error %v", test.color, err, test.wantErr)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("parseColor(%q) = %#x, want %#x", test.color, got, test.want)
+		}
+	}
+}
+
+// errorPaths returns the sorted paths of the validation errors.
+func errorPaths(t *testing.T, errs []error) []string {
+	t.Helper()
+
+	paths := []string{}
+	for _, err := range errs {
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("unexpected error type %T: %v", err, err)
+		}
+		paths = append(paths, validationErr.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		message MessageData
+		want    []string
+	}{
+		{"empty message", MessageData{}, []string{}},
+		{"valid message", MessageData{
+			Content: "Hello",
+			Embed:   &EmbedData{Title: "Title", Color: "#FF0000", URL: "https://example.com", Timestamp: "now"},
+			Buttons: []ButtonData{{Label: "Site", URL: "https://example.com"}},
+		}, []string{}},
+		{"long content", MessageData{Content: strings.Repeat("a", 2001)}, []string{"msg.Content"}},
+		{"bad embed color", MessageData{Embed: &EmbedData{Color: "white-ish"}}, []string{"msg.Embed.Color"}},
+		{"bad color in embeds", MessageData{Embeds: []*EmbedData{{}, {Color: "0x12"}}}, []string{"msg.Embeds[1].Color"}},
+		{"bad timestamp", MessageData{Embed: &EmbedData{Timestamp: "yesterday"}}, []string{"msg.Embed.Timestamp"}},
+		{"relative url", MessageData{Embed: &EmbedData{Image: "/image.png"}}, []string{"msg.Embed.Image"}},
+		{"long title", MessageData{Embed: &EmbedData{Title: strings.Repeat("ä", 257)}}, []string{"msg.Embed.Title"}},
+		{"incomplete field", MessageData{Embed: &EmbedData{
+			Fields: []EmbedField{{Name: "ok", Value: "ok"}, {Name: "missing value"}},
+		}}, []string{"msg.Embed.Fields[1]"}},
+		{"too many embeds", MessageData{Embeds: manyEmbeds(11)}, []string{"msg.Embeds"}},
+		{"button without url", MessageData{Buttons: []ButtonData{{Label: "Site"}}}, []string{"msg.Buttons[0].Url"}},
+		{"button without label", MessageData{Buttons: []ButtonData{{URL: "https://example.com"}}}, []string{"msg.Buttons[0]"}},
+		{"too many buttons", MessageData{Buttons: manyButtons(26)}, []string{"msg.Buttons"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := errorPaths(t, test.message.Validate("msg"))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("error paths = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func manyEmbeds(count int) []*EmbedData {
+	embeds := make([]*EmbedData, count)
+	for i := range embeds {
+		embeds[i] = &EmbedData{Title: "Embed"}
+	}
+	return embeds
+}
+
+func manyButtons(count int) []ButtonData {
+	buttons := make([]ButtonData, count)
+	for i := range buttons {
+		buttons[i] = ButtonData{Label: "Link", URL: "https://example.com"}
+	}
+	return buttons
+}
+
+func TestValidateMessagesPaths(t *testing.T) {
+	type category struct {
+		WelcomeMessage MessageData `json:"WelcomeMessage"`
+	}
+	type guild struct {
+		Messages struct {
+			Created MessageData `json:"TicketCreated"`
+		} `json:"Messages"`
+		Categories []*category `json:"Categories"`
+		Ignored    string
+	}
+	conf := struct {
+		Guilds map[string]*guild `json:"Guilds"`
+	}{Guilds: map[string]*guild{}}
+
+	first := &guild{Categories: []*category{{}, {WelcomeMessage: MessageData{Embed: &EmbedData{Color: "bad"}}}}}
+	first.Messages.Created.Content = strings.Repeat("a", 2001)
+	conf.Guilds["1"] = first
+	conf.Guilds["2"] = &guild{}
+
+	got := errorPaths(t, ValidateMessages(&conf))
+	want := []string{
+		"Guilds.1.Categories[1].WelcomeMessage.Embed.Color",
+		"Guilds.1.Messages.TicketCreated.Content",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("error paths = %q, want %q", got, want)
+	}
+}
+
+func TestValidateReservedRows(t *testing.T) {
+	tests := []struct {
+		buttons  int
+		reserved int
+		wantErr  bool
+	}{
+		{0, 5, false},
+		{5, 4, false},
+		{20, 1, false},
+		{21, 1, true},
+		{6, 4, true},
+		{1, 5, true},
+	}
+
+	for _, test := range tests {
+		message := MessageData{Buttons: make([]ButtonData, test.buttons)}
+		err := message.ValidateReservedRows("msg", test.reserved)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%d buttons with %d reserved rows: error = %v, want error %v", test.buttons, test.reserved, err, test.wantErr)
+		}
+		var validationErr *ValidationError
+		if err != nil && (!errors.As(err, &validationErr) || validationErr.Path != "msg.Buttons") {
+			t.Errorf("unexpected error %v, want path msg.Buttons", err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package music
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var ErrLyricsNotFound = errors.New("no lyrics found")
+
+// LyricsProvider looks up the lyrics of a song by its title.
+type LyricsProvider interface {
+	FindLyrics(title string) (string, error)
+}
+
+// HTTPLyricsProvider fetches lyrics from an LRCLIB compatible api (GET <BaseURL>/search?q=<title>).
+type HTTPLyricsProvider struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+type lyricsSearchResult struct {
+	TrackName    string `json:"trackName"`
+	ArtistName   string `json:"artistName"`
+	Instrumental bool   `json:"instrumental"`
+	PlainLyrics  string `json:"plainLyrics"`
+}
+
+func (p *HTTPLyricsProvider) FindLyrics(title string) (string, error) {
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	query := CleanSongTitle(title)
+	resp, err := client.Get(strings.TrimRight(p.BaseURL, "/") + "/search?q=" + url.QueryEscape(query))
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch lyrics: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("lyrics api returned %s", resp.Status)
+	}
+
+	var results []lyricsSearchResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return "", fmt.Errorf("failed to decode lyrics response: %v", err)
+	}
+
+	for _, result := range results {
+		if result.Instrumental || strings.TrimSpace(result.PlainLyrics) == "" {
+			continue
+		}
+		return result.PlainLyrics, nil
+	}
+	return "", ErrLyricsNotFound
+}
+
+var titleNoise = regexp.MustCompile(`\s*[\(\[][^\)\]]*[\)\]]`)
+
+// CleanSongTitle strips bracketed parts such as "(Official Video)" from a YouTube title.
+func CleanSongTitle(title string) string {
+	return strings.TrimSpace(titleNoise.ReplaceAllString(title, ""))
+}
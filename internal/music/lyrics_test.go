@@ -0,0 +1,95 @@
+package music
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func newLyricsServer(t *testing.T, status int, body string) (*HTTPLyricsProvider, *url.Values) {
+	server, query := stubServer(t, "/search", status, body)
+	return &HTTPLyricsProvider{BaseURL: server.URL + "/", Client: server.Client()}, query
+}
+
+func TestFindLyrics(t *testing.T) {
+	provider, query := newLyricsServer(t, http.StatusOK, `[
+		{"trackName": "Song", "instrumental": true, "plainLyrics": ""},
+		{"trackName": "Song", "instrumental": false, "plainLyrics": "  "},
+		{"trackName": "Song", "instrumental": false, "plainLyrics": "la la la"}
+	]`)
+
+	lyrics, err := provider.FindLyrics("Artist - Song (Official Video)")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lyrics != "la la la" {
+		t.Errorf("lyrics = %q, want %q", lyrics, "la la la")
+	}
+	if q := query.Get("q"); q != "Artist - Song" {
+		t.Errorf("searched for %q, want the cleaned title", q)
+	}
+}
+
+func TestFindLyricsNotFound(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"no results", `[]`},
+		{"only instrumental", `[{"trackName": "Song", "instrumental": true, "plainLyrics": "ignored"}]`},
+		{"empty lyrics", `[{"trackName": "Song", "plainLyrics": ""}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider, _ := newLyricsServer(t, http.StatusOK, test.body)
+			if _, err := provider.FindLyrics("Song"); !errors.Is(err, ErrLyricsNotFound) {
+				t.Errorf("error = %v, want ErrLyricsNotFound", err)
+			}
+		})
+	}
+}
+
+func TestFindLyricsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"server error", http.StatusInternalServerError, `[]`},
+		{"not found status", http.StatusNotFound, ``},
+		{"bad json", http.StatusOK, `{"trackName":`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider, _ := newLyricsServer(t, test.status, test.body)
+			_, err := provider.FindLyrics("Song")
+			if err == nil || errors.Is(err, ErrLyricsNotFound) {
+				t.Errorf("error = %v, want a request error", err)
+			}
+		})
+	}
+}
+
+func TestCleanSongTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Song", "Song"},
+		{"Artist - Song (Official Video)", "Artist - Song"},
+		{"Artist - Song [Lyrics]", "Artist - Song"},
+		{"Artist - Song (feat. Other) [HD]", "Artist - Song"},
+		{"  Artist - Song  ", "Artist - Song"},
+		{"(Intro) Song", "Song"},
+		{"Song (unclosed", "Song (unclosed"},
+	}
+
+	for _, test := range tests {
+		if got := CleanSongTitle(test.title); got != test.want {
+			t.Errorf("CleanSongTitle(%q) = %q, want %q", test.title, got, test.want)
+		}
+	}
+}
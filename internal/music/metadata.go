@@ -0,0 +1,71 @@
+package music
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var streamingLink = regexp.MustCompile(`https?://(open\.spotify\.com/(intl-[a-z]+/)?(track|album|playlist)|music\.apple\.com/[a-z]{2}/(song|album|playlist))/\S+`)
+
+// TrackMetadata describes a track resolved from a streaming service link.
+type TrackMetadata struct {
+	Title  string `json:"title"`
+	Artist string `json:"artist"`
+}
+
+func (t TrackMetadata) String() string {
+	if t.Artist == "" {
+		return t.Title
+	}
+	return t.Artist + " - " + t.Title
+}
+
+// MetadataResolver resolves a Spotify or Apple Music link to the tracks it contains.
+type MetadataResolver interface {
+	Resolve(link string) ([]TrackMetadata, error)
+}
+
+// HTTPMetadataResolver resolves links through a metadata api (GET <BaseURL>/resolve?url=<link>)
+// that responds with {"tracks": [{"title": "...", "artist": "..."}]}.
+type HTTPMetadataResolver struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func (r *HTTPMetadataResolver) Resolve(link string) ([]TrackMetadata, error) {
+	client := r.Client
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+
+	resp, err := client.Get(strings.TrimRight(r.BaseURL, "/") + "/resolve?url=" + url.QueryEscape(link))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve link: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata api returned %s", resp.Status)
+	}
+
+	var result struct {
+		Tracks []TrackMetadata `json:"tracks"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata response: %v", err)
+	}
+	if len(result.Tracks) == 0 {
+		return nil, fmt.Errorf("no tracks found for %s", link)
+	}
+	return result.Tracks, nil
+}
+
+// FindStreamingLink returns the first Spotify or Apple Music track, album or playlist link in text.
+func FindStreamingLink(text string) string {
+	return streamingLink.FindString(text)
+}
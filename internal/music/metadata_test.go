@@ -0,0 +1,82 @@
+package music
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func newMetadataServer(t *testing.T, status int, body string) (*HTTPMetadataResolver, *url.Values) {
+	server, query := stubServer(t, "/resolve", status, body)
+	return &HTTPMetadataResolver{BaseURL: server.URL, Client: server.Client()}, query
+}
+
+func TestResolve(t *testing.T) {
+	resolver, query := newMetadataServer(t, http.StatusOK, `{"tracks": [
+		{"title": "First", "artist": "Artist"},
+		{"title": "Second"}
+	]}`)
+
+	spotify := "https://open.spotify.com/playlist/abc?si=1&x=2"
+	tracks, err := resolver.Resolve(spotify)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []TrackMetadata{{Title: "First", Artist: "Artist"}, {Title: "Second"}}
+	if !reflect.DeepEqual(tracks, want) {
+		t.Errorf("tracks = %+v, want %+v", tracks, want)
+	}
+	if link := query.Get("url"); link != spotify {
+		t.Errorf("resolved %q, want %q", link, spotify)
+	}
+	if tracks[0].String() != "Artist - First" || tracks[1].String() != "Second" {
+		t.Errorf("unexpected track names %q and %q", tracks[0], tracks[1])
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"empty tracks", http.StatusOK, `{"tracks": []}`},
+		{"missing tracks", http.StatusOK, `{}`},
+		{"bad json", http.StatusOK, `{"tracks": [`},
+		{"server error", http.StatusBadGateway, `{"tracks": [{"title": "Song"}]}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resolver, _ := newMetadataServer(t, test.status, test.body)
+			if tracks, err := resolver.Resolve("https://open.spotify.com/track/abc"); err == nil {
+				t.Errorf("expected an error, got tracks %+v", tracks)
+			}
+		})
+	}
+}
+
+func TestFindStreamingLink(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC", "https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC"},
+		{"play https://open.spotify.com/intl-de/album/abc?si=x please", "https://open.spotify.com/intl-de/album/abc?si=x"},
+		{"https://open.spotify.com/playlist/abc", "https://open.spotify.com/playlist/abc"},
+		{"https://music.apple.com/us/album/name/123?i=456", "https://music.apple.com/us/album/name/123?i=456"},
+		{"https://music.apple.com/gb/playlist/pl.abc", "https://music.apple.com/gb/playlist/pl.abc"},
+		{"https://open.spotify.com/artist/abc", ""},
+		{"https://open.spotify.com/track/", ""},
+		{"https://www.youtube.com/watch?v=abc", ""},
+		{"never gonna give you up", ""},
+	}
+
+	for _, test := range tests {
+		if got := FindStreamingLink(test.text); got != test.want {
+			t.Errorf("FindStreamingLink(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
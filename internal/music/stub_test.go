@@ -0,0 +1,26 @@
+package music
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// stubServer serves body with status on path and records the query of the last request.
+func stubServer(t *testing.T, path string, status int, body string) (*httptest.Server, *url.Values) {
+	t.Helper()
+
+	query := &url.Values{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			t.Errorf("unexpected path %s, want %s", r.URL.Path, path)
+		}
+		*query = r.URL.Query()
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, query
+}
@@ -1,9 +1,11 @@
 package music
 
 import (
+	"fmt"
 	"io"
 	"os/exec"
 	"phoenixbot/internal/util"
+	"strings"
 )
 
 func GetYouTubeStream(videoURL string) (io.ReadCloser, error) {
@@ -31,6 +33,9 @@ func FindYouTubeVideo(videoName string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	s := string(b)
+	s := strings.TrimSpace(string(b))
+	if s == "" {
+		return "", fmt.Errorf("no youtube results for %s", videoName)
+	}
 	return util.YoutubeIdToUrl(s), nil
 }
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Directory where runtime state is persisted between restarts
+var dataPath = "./data/"
+
+// Load reads the json file from the data directory into v. A missing file leaves v untouched.
+func Load(filename string, v interface{}) error {
+	data, err := os.ReadFile(filepath.Join(dataPath, filename))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("couldnt read data file %s: %v", filename, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("couldnt parse data file %s: %v", filename, err)
+	}
+	return nil
+}
+
+// Save writes v as json to the data directory, replacing the previous file atomically.
+func Save(filename string, v interface{}) error {
+	if err := os.MkdirAll(dataPath, 0755); err != nil {
+		return fmt.Errorf("couldnt create data directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("couldnt encode data file %s: %v", filename, err)
+	}
+
+	path := filepath.Join(dataPath, filename)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("couldnt write data file %s: %v", filename, err)
+	}
+	return os.Rename(path+".tmp", path)
+}
@@ -0,0 +1,35 @@
+package util
+
+import "strings"
+
+// SplitText splits text into chunks of at most max characters, breaking on newlines where possible.
+func SplitText(text string, max int) []string {
+	chunks := []string{}
+	current := []rune{}
+
+	for _, line := range strings.Split(text, "\n") {
+		r := []rune(line)
+		for len(r) > max {
+			if len(current) > 0 {
+				chunks = append(chunks, string(current))
+				current = []rune{}
+			}
+			chunks = append(chunks, string(r[:max]))
+			r = r[max:]
+		}
+
+		if len(current) > 0 && len(current)+1+len(r) > max {
+			chunks = append(chunks, string(current))
+			current = []rune{}
+		}
+		if len(current) > 0 {
+			current = append(current, '\n')
+		}
+		current = append(current, r...)
+	}
+
+	if len(current) > 0 {
+		chunks = append(chunks, string(current))
+	}
+	return chunks
+}
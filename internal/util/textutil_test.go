@@ -0,0 +1,47 @@
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		max  int
+		want []string
+	}{
+		{"empty", "", 10, []string{}},
+		{"fits", "short", 10, []string{"short"}},
+		{"exact length", "0123456789", 10, []string{"0123456789"}},
+		{"joins lines", "ab\ncd\nef", 5, []string{"ab\ncd", "ef"}},
+		{"breaks on newline", "hello\nworld", 8, []string{"hello", "world"}},
+		{"long line", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"long line after short", "ab\ncdefgh", 4, []string{"ab", "cdef", "gh"}},
+		{"multibyte runes", "äöüßé", 2, []string{"äö", "üß", "é"}},
+		{"emoji", "🎵🎶🎵", 1, []string{"🎵", "🎶", "🎵"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := SplitText(test.text, test.max)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("SplitText(%q, %d) = %q, want %q", test.text, test.max, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSplitTextKeepsText(t *testing.T) {
+	text := strings.Repeat("Ünïcödé line ♪\n", 300)
+	for _, chunk := range SplitText(text, 2000) {
+		if length := len([]rune(chunk)); length > 2000 {
+			t.Errorf("chunk has %d characters, want at most 2000", length)
+		}
+		if !strings.HasPrefix(chunk, "Ü") {
+			t.Errorf("chunk doesnt start on a line: %q", chunk[:20])
+		}
+	}
+}